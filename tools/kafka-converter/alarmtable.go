@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/proto"
+)
+
+// AlarmQuery represents the criteria to find active alarms; empty fields match everything.
+type AlarmQuery struct {
+	Severities   []producer.Severity // Any of the given severities
+	MinSeverity  producer.Severity   // Severity equal or greater than the given one
+	Node         string              // Node ID or foreign-source:foreign-id
+	UEIPrefix    string
+	ReductionKey string
+}
+
+// Match returns true if a given alarm matches the query.
+func (q *AlarmQuery) Match(alarm *producer.Alarm) bool {
+	if len(q.Severities) > 0 {
+		found := false
+		for _, s := range q.Severities {
+			found = found || alarm.Severity == s
+		}
+		if !found {
+			return false
+		}
+	}
+	if alarm.Severity < q.MinSeverity {
+		return false
+	}
+	if q.Node != "" {
+		c := alarm.NodeCriteria
+		if c == nil {
+			return false
+		}
+		if q.Node != strconv.FormatUint(c.Id, 10) && q.Node != c.ForeignSource+":"+c.ForeignId {
+			return false
+		}
+	}
+	if !strings.HasPrefix(alarm.Uei, q.UEIPrefix) {
+		return false
+	}
+	return q.ReductionKey == "" || alarm.ReductionKey == q.ReductionKey
+}
+
+// AlarmTable represents an in-memory view of the active alarms, built from the alarms topic.
+type AlarmTable struct {
+	Topic   string
+	Encoder *JSONEncoder
+	mutex   sync.RWMutex
+	alarms  map[string]*producer.Alarm // Indexed by the Kafka record key
+	reader  *TopicReader
+}
+
+// NewAlarmTable creates an empty table for a given alarms topic.
+func NewAlarmTable(topic string, encoder *JSONEncoder) *AlarmTable {
+	return &AlarmTable{
+		Topic:   topic,
+		Encoder: encoder,
+		alarms:  make(map[string]*producer.Alarm),
+	}
+}
+
+// Start rebuilds the table from the beginning of the alarms topic, and keeps it updated.
+func (t *AlarmTable) Start(config *kafka.ConfigMap) error {
+	t.reader = &TopicReader{Topic: t.Topic, Handler: t.update}
+	if err := t.reader.Start(config); err != nil {
+		return err
+	}
+	log.Printf("alarm table initialized with %d alarms\n", t.Size())
+	return nil
+}
+
+// Stop stops reading the alarms topic.
+func (t *AlarmTable) Stop() {
+	if t.reader != nil {
+		t.reader.Stop()
+	}
+}
+
+func (t *AlarmTable) update(msg *kafka.Message) {
+	key := string(msg.Key)
+	if msg.Value == nil {
+		t.Update(key, nil)
+		return
+	}
+	alarm := &producer.Alarm{}
+	if err := proto.Unmarshal(msg.Value, alarm); err != nil {
+		log.Printf("invalid alarm message received from %s: %v\n", msg.TopicPartition, err)
+		return
+	}
+	t.Update(key, alarm)
+}
+
+// Update adds or replaces the alarm associated with a given record key; a nil alarm removes it.
+func (t *AlarmTable) Update(key string, alarm *producer.Alarm) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if alarm == nil {
+		delete(t.alarms, key)
+	} else {
+		t.alarms[key] = alarm
+	}
+}
+
+// Size returns the number of alarms in the table.
+func (t *AlarmTable) Size() int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return len(t.alarms)
+}
+
+// Find returns the alarms matching a given query, sorted by ID.
+func (t *AlarmTable) Find(q *AlarmQuery) []*producer.Alarm {
+	t.mutex.RLock()
+	result := make([]*producer.Alarm, 0)
+	for _, alarm := range t.alarms {
+		if q.Match(alarm) {
+			result = append(result, alarm)
+		}
+	}
+	t.mutex.RUnlock()
+	sort.Slice(result, func(i, j int) bool { return result[i].Id < result[j].Id })
+	return result
+}
+
+// Get returns the alarm with a given ID, or nil if it doesn't exist.
+func (t *AlarmTable) Get(id uint64) *producer.Alarm {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	for _, alarm := range t.alarms {
+		if alarm.Id == id {
+			return alarm
+		}
+	}
+	return nil
+}
+
+// Counts returns the number of alarms per severity.
+func (t *AlarmTable) Counts() map[string]int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	counts := make(map[string]int)
+	for _, alarm := range t.alarms {
+		counts[alarm.Severity.String()]++
+	}
+	return counts
+}
+
+// ServeHTTP implements the http.Handler interface, exposing the following endpoints:
+//
+//	GET /alarms?severity=MAJOR,CRITICAL&min_severity=MINOR&node=1&uei=uei.opennms.org/nodes/&reduction_key=...
+//	GET /alarms/counts
+//	GET /alarms/{id}
+func (t *AlarmTable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/alarms"), "/")
+	switch path {
+	case "":
+		q, err := parseAlarmQuery(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		t.writeAlarms(w, t.Find(q))
+	case "counts":
+		t.writeJSON(w, t.Counts())
+	default:
+		id, err := strconv.ParseUint(path, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid alarm ID "+path, http.StatusBadRequest)
+			return
+		}
+		alarm := t.Get(id)
+		if alarm == nil {
+			http.Error(w, "alarm not found", http.StatusNotFound)
+			return
+		}
+		data, err := t.Encoder.Marshal(alarm)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		t.writeRaw(w, data)
+	}
+}
+
+func (t *AlarmTable) writeAlarms(w http.ResponseWriter, alarms []*producer.Alarm) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, alarm := range alarms {
+		data, err := t.Encoder.Marshal(alarm)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(data)
+	}
+	buf.WriteByte(']')
+	t.writeRaw(w, buf.Bytes())
+}
+
+func (t *AlarmTable) writeJSON(w http.ResponseWriter, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	t.writeRaw(w, data)
+}
+
+func (t *AlarmTable) writeRaw(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		log.Printf("cannot write response: %v\n", err)
+	}
+}
+
+func parseSeverity(name string) (producer.Severity, error) {
+	value, ok := producer.Severity_value[strings.ToUpper(strings.TrimSpace(name))]
+	if !ok {
+		return 0, fmt.Errorf("invalid severity %s", name)
+	}
+	return producer.Severity(value), nil
+}
+
+func parseAlarmQuery(r *http.Request) (*AlarmQuery, error) {
+	params := r.URL.Query()
+	q := &AlarmQuery{
+		Node:         params.Get("node"),
+		UEIPrefix:    params.Get("uei"),
+		ReductionKey: params.Get("reduction_key"),
+	}
+	if value := params.Get("severity"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			s, err := parseSeverity(name)
+			if err != nil {
+				return nil, err
+			}
+			q.Severities = append(q.Severities, s)
+		}
+	}
+	if value := params.Get("min_severity"); value != "" {
+		s, err := parseSeverity(value)
+		if err != nil {
+			return nil, err
+		}
+		q.MinSeverity = s
+	}
+	return q, nil
+}
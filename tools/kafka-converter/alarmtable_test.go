@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"gotest.tools/assert"
+)
+
+func buildAlarmTable() *AlarmTable {
+	table := NewAlarmTable("OpenNMS-alarms", &JSONEncoder{Format: protoJSONFormat, EnumsAsStrings: true})
+	table.Update("uei.opennms.org/nodes/nodeDown::1", &producer.Alarm{
+		Id:           1,
+		Uei:          "uei.opennms.org/nodes/nodeDown",
+		ReductionKey: "uei.opennms.org/nodes/nodeDown::1",
+		Severity:     producer.Severity_MAJOR,
+		NodeCriteria: &producer.NodeCriteria{Id: 1, ForeignSource: "Production", ForeignId: "srv01"},
+	})
+	table.Update("uei.opennms.org/threshold/highThresholdExceeded::2", &producer.Alarm{
+		Id:           2,
+		Uei:          "uei.opennms.org/threshold/highThresholdExceeded",
+		ReductionKey: "uei.opennms.org/threshold/highThresholdExceeded::2",
+		Severity:     producer.Severity_WARNING,
+		NodeCriteria: &producer.NodeCriteria{Id: 2},
+	})
+	table.Update("uei.opennms.org/nodes/nodeDown::3", &producer.Alarm{
+		Id:           3,
+		Uei:          "uei.opennms.org/nodes/nodeDown",
+		ReductionKey: "uei.opennms.org/nodes/nodeDown::3",
+		Severity:     producer.Severity_CRITICAL,
+	})
+	return table
+}
+
+func TestAlarmTableFind(t *testing.T) {
+	table := buildAlarmTable()
+	assert.Equal(t, 3, table.Size())
+	assert.Equal(t, 2, len(table.Find(&AlarmQuery{MinSeverity: producer.Severity_MAJOR})))
+	assert.Equal(t, 1, len(table.Find(&AlarmQuery{Severities: []producer.Severity{producer.Severity_WARNING}})))
+	assert.Equal(t, uint64(1), table.Find(&AlarmQuery{Node: "Production:srv01"})[0].Id)
+	assert.Equal(t, uint64(2), table.Find(&AlarmQuery{Node: "2"})[0].Id)
+	assert.Equal(t, 2, len(table.Find(&AlarmQuery{UEIPrefix: "uei.opennms.org/nodes/"})))
+	assert.Equal(t, 1, len(table.Find(&AlarmQuery{ReductionKey: "uei.opennms.org/nodes/nodeDown::3"})))
+
+	table.Update("uei.opennms.org/nodes/nodeDown::3", nil)
+	assert.Equal(t, 2, table.Size())
+	assert.DeepEqual(t, map[string]int{"MAJOR": 1, "WARNING": 1}, table.Counts())
+}
+
+func TestAlarmTableHTTP(t *testing.T) {
+	table := buildAlarmTable()
+	get := func(url string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		table.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+		return w
+	}
+
+	w := get("/alarms?severity=major,critical&uei=uei.opennms.org/nodes/&node=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[{"id":"1","uei":"uei.opennms.org/nodes/nodeDown","node_criteria":{"id":"1","foreign_source":"Production","foreign_id":"srv01"},"reduction_key":"uei.opennms.org/nodes/nodeDown::1","severity":"MAJOR"}]`, w.Body.String())
+
+	w = get("/alarms/counts")
+	assert.Equal(t, `{"CRITICAL":1,"MAJOR":1,"WARNING":1}`, w.Body.String())
+
+	w = get("/alarms/2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Assert(t, len(w.Body.String()) > 0)
+
+	assert.Equal(t, http.StatusNotFound, get("/alarms/10").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/alarms/abc").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/alarms?severity=unknown").Code)
+}
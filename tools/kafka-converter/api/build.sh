@@ -4,3 +4,5 @@ type protoc >/dev/null 2>&1 || { echo >&2 "protoc required but it's not installe
 
 protoc -I . opennms-kafka-producer.proto --go_out=./producer
 protoc -I . collectionset.proto --go_out=./producer
+protoc -I . sink-message.proto --go_out=./sink
+protoc -I . flowdocument.proto --go_out=./flows
@@ -0,0 +1,1043 @@
+// Source: https://github.com/OpenNMS/opennms/blob/master/features/flows/kafka-persistence/src/main/proto/flowdocument.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.1
+// 	protoc        (unknown)
+// source: flowdocument.proto
+
+package flows
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	wrapperspb "google.golang.org/protobuf/types/known/wrapperspb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Direction int32
+
+const (
+	Direction_INGRESS Direction = 0
+	Direction_EGRESS  Direction = 1
+	Direction_UNKNOWN Direction = 255
+)
+
+// Enum value maps for Direction.
+var (
+	Direction_name = map[int32]string{
+		0:   "INGRESS",
+		1:   "EGRESS",
+		255: "UNKNOWN",
+	}
+	Direction_value = map[string]int32{
+		"INGRESS": 0,
+		"EGRESS":  1,
+		"UNKNOWN": 255,
+	}
+)
+
+func (x Direction) Enum() *Direction {
+	p := new(Direction)
+	*p = x
+	return p
+}
+
+func (x Direction) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Direction) Descriptor() protoreflect.EnumDescriptor {
+	return file_flowdocument_proto_enumTypes[0].Descriptor()
+}
+
+func (Direction) Type() protoreflect.EnumType {
+	return &file_flowdocument_proto_enumTypes[0]
+}
+
+func (x Direction) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Direction.Descriptor instead.
+func (Direction) EnumDescriptor() ([]byte, []int) {
+	return file_flowdocument_proto_rawDescGZIP(), []int{0}
+}
+
+type SamplingAlgorithm int32
+
+const (
+	SamplingAlgorithm_UNASSIGNED                                               SamplingAlgorithm = 0
+	SamplingAlgorithm_SYSTEMATIC_COUNT_BASED_SAMPLING                          SamplingAlgorithm = 1
+	SamplingAlgorithm_SYSTEMATIC_TIME_BASED_SAMPLING                           SamplingAlgorithm = 2
+	SamplingAlgorithm_RANDOM_N_OUT_OF_N_SAMPLING                               SamplingAlgorithm = 3
+	SamplingAlgorithm_UNIFORM_PROBABILISTIC_SAMPLING                           SamplingAlgorithm = 4
+	SamplingAlgorithm_PROPERTY_MATCH_FILTERING                                 SamplingAlgorithm = 5
+	SamplingAlgorithm_HASH_BASED_FILTERING                                     SamplingAlgorithm = 6
+	SamplingAlgorithm_FLOW_STATE_DEPENDENT_INTERMEDIATE_FLOW_SELECTION_PROCESS SamplingAlgorithm = 7
+)
+
+// Enum value maps for SamplingAlgorithm.
+var (
+	SamplingAlgorithm_name = map[int32]string{
+		0: "UNASSIGNED",
+		1: "SYSTEMATIC_COUNT_BASED_SAMPLING",
+		2: "SYSTEMATIC_TIME_BASED_SAMPLING",
+		3: "RANDOM_N_OUT_OF_N_SAMPLING",
+		4: "UNIFORM_PROBABILISTIC_SAMPLING",
+		5: "PROPERTY_MATCH_FILTERING",
+		6: "HASH_BASED_FILTERING",
+		7: "FLOW_STATE_DEPENDENT_INTERMEDIATE_FLOW_SELECTION_PROCESS",
+	}
+	SamplingAlgorithm_value = map[string]int32{
+		"UNASSIGNED":                                               0,
+		"SYSTEMATIC_COUNT_BASED_SAMPLING":                          1,
+		"SYSTEMATIC_TIME_BASED_SAMPLING":                           2,
+		"RANDOM_N_OUT_OF_N_SAMPLING":                               3,
+		"UNIFORM_PROBABILISTIC_SAMPLING":                           4,
+		"PROPERTY_MATCH_FILTERING":                                 5,
+		"HASH_BASED_FILTERING":                                     6,
+		"FLOW_STATE_DEPENDENT_INTERMEDIATE_FLOW_SELECTION_PROCESS": 7,
+	}
+)
+
+func (x SamplingAlgorithm) Enum() *SamplingAlgorithm {
+	p := new(SamplingAlgorithm)
+	*p = x
+	return p
+}
+
+func (x SamplingAlgorithm) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SamplingAlgorithm) Descriptor() protoreflect.EnumDescriptor {
+	return file_flowdocument_proto_enumTypes[1].Descriptor()
+}
+
+func (SamplingAlgorithm) Type() protoreflect.EnumType {
+	return &file_flowdocument_proto_enumTypes[1]
+}
+
+func (x SamplingAlgorithm) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SamplingAlgorithm.Descriptor instead.
+func (SamplingAlgorithm) EnumDescriptor() ([]byte, []int) {
+	return file_flowdocument_proto_rawDescGZIP(), []int{1}
+}
+
+type NetflowVersion int32
+
+const (
+	NetflowVersion_V5    NetflowVersion = 0
+	NetflowVersion_V9    NetflowVersion = 1
+	NetflowVersion_IPFIX NetflowVersion = 2
+	NetflowVersion_SFLOW NetflowVersion = 3
+)
+
+// Enum value maps for NetflowVersion.
+var (
+	NetflowVersion_name = map[int32]string{
+		0: "V5",
+		1: "V9",
+		2: "IPFIX",
+		3: "SFLOW",
+	}
+	NetflowVersion_value = map[string]int32{
+		"V5":    0,
+		"V9":    1,
+		"IPFIX": 2,
+		"SFLOW": 3,
+	}
+)
+
+func (x NetflowVersion) Enum() *NetflowVersion {
+	p := new(NetflowVersion)
+	*p = x
+	return p
+}
+
+func (x NetflowVersion) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (NetflowVersion) Descriptor() protoreflect.EnumDescriptor {
+	return file_flowdocument_proto_enumTypes[2].Descriptor()
+}
+
+func (NetflowVersion) Type() protoreflect.EnumType {
+	return &file_flowdocument_proto_enumTypes[2]
+}
+
+func (x NetflowVersion) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use NetflowVersion.Descriptor instead.
+func (NetflowVersion) EnumDescriptor() ([]byte, []int) {
+	return file_flowdocument_proto_rawDescGZIP(), []int{2}
+}
+
+type Locality int32
+
+const (
+	Locality_PUBLIC  Locality = 0
+	Locality_PRIVATE Locality = 1
+)
+
+// Enum value maps for Locality.
+var (
+	Locality_name = map[int32]string{
+		0: "PUBLIC",
+		1: "PRIVATE",
+	}
+	Locality_value = map[string]int32{
+		"PUBLIC":  0,
+		"PRIVATE": 1,
+	}
+)
+
+func (x Locality) Enum() *Locality {
+	p := new(Locality)
+	*p = x
+	return p
+}
+
+func (x Locality) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Locality) Descriptor() protoreflect.EnumDescriptor {
+	return file_flowdocument_proto_enumTypes[3].Descriptor()
+}
+
+func (Locality) Type() protoreflect.EnumType {
+	return &file_flowdocument_proto_enumTypes[3]
+}
+
+func (x Locality) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Locality.Descriptor instead.
+func (Locality) EnumDescriptor() ([]byte, []int) {
+	return file_flowdocument_proto_rawDescGZIP(), []int{3}
+}
+
+type NodeInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ForeignSource string   `protobuf:"bytes,1,opt,name=foreign_source,json=foreignSource,proto3" json:"foreign_source,omitempty"`
+	ForeignId     string   `protobuf:"bytes,2,opt,name=foreign_id,json=foreignId,proto3" json:"foreign_id,omitempty"`
+	NodeId        uint32   `protobuf:"varint,3,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Categories    []string `protobuf:"bytes,4,rep,name=categories,proto3" json:"categories,omitempty"`
+}
+
+func (x *NodeInfo) Reset() {
+	*x = NodeInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_flowdocument_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NodeInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeInfo) ProtoMessage() {}
+
+func (x *NodeInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_flowdocument_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeInfo.ProtoReflect.Descriptor instead.
+func (*NodeInfo) Descriptor() ([]byte, []int) {
+	return file_flowdocument_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *NodeInfo) GetForeignSource() string {
+	if x != nil {
+		return x.ForeignSource
+	}
+	return ""
+}
+
+func (x *NodeInfo) GetForeignId() string {
+	if x != nil {
+		return x.ForeignId
+	}
+	return ""
+}
+
+func (x *NodeInfo) GetNodeId() uint32 {
+	if x != nil {
+		return x.NodeId
+	}
+	return 0
+}
+
+func (x *NodeInfo) GetCategories() []string {
+	if x != nil {
+		return x.Categories
+	}
+	return nil
+}
+
+type FlowDocument struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Timestamp         uint64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	NumBytes          *wrapperspb.UInt64Value `protobuf:"bytes,2,opt,name=num_bytes,json=numBytes,proto3" json:"num_bytes,omitempty"`
+	Direction         Direction               `protobuf:"varint,3,opt,name=direction,proto3,enum=org.opennms.netmgt.flows.persistence.model.Direction" json:"direction,omitempty"`
+	DstAddress        string                  `protobuf:"bytes,4,opt,name=dst_address,json=dstAddress,proto3" json:"dst_address,omitempty"`
+	DstHostname       string                  `protobuf:"bytes,5,opt,name=dst_hostname,json=dstHostname,proto3" json:"dst_hostname,omitempty"`
+	DstAs             *wrapperspb.UInt64Value `protobuf:"bytes,6,opt,name=dst_as,json=dstAs,proto3" json:"dst_as,omitempty"`
+	DstMaskLen        *wrapperspb.UInt32Value `protobuf:"bytes,7,opt,name=dst_mask_len,json=dstMaskLen,proto3" json:"dst_mask_len,omitempty"`
+	DstPort           *wrapperspb.UInt32Value `protobuf:"bytes,8,opt,name=dst_port,json=dstPort,proto3" json:"dst_port,omitempty"`
+	EngineId          *wrapperspb.UInt32Value `protobuf:"bytes,9,opt,name=engine_id,json=engineId,proto3" json:"engine_id,omitempty"`
+	EngineType        *wrapperspb.UInt32Value `protobuf:"bytes,10,opt,name=engine_type,json=engineType,proto3" json:"engine_type,omitempty"`
+	DeltaSwitched     *wrapperspb.UInt64Value `protobuf:"bytes,11,opt,name=delta_switched,json=deltaSwitched,proto3" json:"delta_switched,omitempty"`
+	FirstSwitched     *wrapperspb.UInt64Value `protobuf:"bytes,12,opt,name=first_switched,json=firstSwitched,proto3" json:"first_switched,omitempty"`
+	LastSwitched      *wrapperspb.UInt64Value `protobuf:"bytes,13,opt,name=last_switched,json=lastSwitched,proto3" json:"last_switched,omitempty"`
+	NumFlowRecords    *wrapperspb.UInt32Value `protobuf:"bytes,14,opt,name=num_flow_records,json=numFlowRecords,proto3" json:"num_flow_records,omitempty"`
+	NumPackets        *wrapperspb.UInt64Value `protobuf:"bytes,15,opt,name=num_packets,json=numPackets,proto3" json:"num_packets,omitempty"`
+	FlowSeqNum        *wrapperspb.UInt64Value `protobuf:"bytes,16,opt,name=flow_seq_num,json=flowSeqNum,proto3" json:"flow_seq_num,omitempty"`
+	InputSnmpIfindex  *wrapperspb.UInt32Value `protobuf:"bytes,17,opt,name=input_snmp_ifindex,json=inputSnmpIfindex,proto3" json:"input_snmp_ifindex,omitempty"`
+	OutputSnmpIfindex *wrapperspb.UInt32Value `protobuf:"bytes,18,opt,name=output_snmp_ifindex,json=outputSnmpIfindex,proto3" json:"output_snmp_ifindex,omitempty"`
+	IpProtocolVersion *wrapperspb.UInt32Value `protobuf:"bytes,19,opt,name=ip_protocol_version,json=ipProtocolVersion,proto3" json:"ip_protocol_version,omitempty"`
+	NextHopAddress    string                  `protobuf:"bytes,20,opt,name=next_hop_address,json=nextHopAddress,proto3" json:"next_hop_address,omitempty"`
+	NextHopHostname   string                  `protobuf:"bytes,21,opt,name=next_hop_hostname,json=nextHopHostname,proto3" json:"next_hop_hostname,omitempty"`
+	Protocol          *wrapperspb.UInt32Value `protobuf:"bytes,22,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	SamplingAlgorithm SamplingAlgorithm       `protobuf:"varint,23,opt,name=sampling_algorithm,json=samplingAlgorithm,proto3,enum=org.opennms.netmgt.flows.persistence.model.SamplingAlgorithm" json:"sampling_algorithm,omitempty"`
+	SamplingInterval  *wrapperspb.DoubleValue `protobuf:"bytes,24,opt,name=sampling_interval,json=samplingInterval,proto3" json:"sampling_interval,omitempty"`
+	SrcAddress        string                  `protobuf:"bytes,26,opt,name=src_address,json=srcAddress,proto3" json:"src_address,omitempty"`
+	SrcHostname       string                  `protobuf:"bytes,27,opt,name=src_hostname,json=srcHostname,proto3" json:"src_hostname,omitempty"`
+	SrcAs             *wrapperspb.UInt64Value `protobuf:"bytes,28,opt,name=src_as,json=srcAs,proto3" json:"src_as,omitempty"`
+	SrcMaskLen        *wrapperspb.UInt32Value `protobuf:"bytes,29,opt,name=src_mask_len,json=srcMaskLen,proto3" json:"src_mask_len,omitempty"`
+	SrcPort           *wrapperspb.UInt32Value `protobuf:"bytes,30,opt,name=src_port,json=srcPort,proto3" json:"src_port,omitempty"`
+	TcpFlags          *wrapperspb.UInt32Value `protobuf:"bytes,31,opt,name=tcp_flags,json=tcpFlags,proto3" json:"tcp_flags,omitempty"`
+	Tos               *wrapperspb.UInt32Value `protobuf:"bytes,32,opt,name=tos,proto3" json:"tos,omitempty"`
+	NetflowVersion    NetflowVersion          `protobuf:"varint,33,opt,name=netflow_version,json=netflowVersion,proto3,enum=org.opennms.netmgt.flows.persistence.model.NetflowVersion" json:"netflow_version,omitempty"`
+	Vlan              string                  `protobuf:"bytes,34,opt,name=vlan,proto3" json:"vlan,omitempty"`
+	SrcNode           *NodeInfo               `protobuf:"bytes,35,opt,name=src_node,json=srcNode,proto3" json:"src_node,omitempty"`
+	ExporterNode      *NodeInfo               `protobuf:"bytes,36,opt,name=exporter_node,json=exporterNode,proto3" json:"exporter_node,omitempty"`
+	DstNode           *NodeInfo               `protobuf:"bytes,37,opt,name=dst_node,json=dstNode,proto3" json:"dst_node,omitempty"`
+	Application       string                  `protobuf:"bytes,38,opt,name=application,proto3" json:"application,omitempty"`
+	Host              string                  `protobuf:"bytes,39,opt,name=host,proto3" json:"host,omitempty"`
+	Location          string                  `protobuf:"bytes,40,opt,name=location,proto3" json:"location,omitempty"`
+	SrcLocality       Locality                `protobuf:"varint,41,opt,name=src_locality,json=srcLocality,proto3,enum=org.opennms.netmgt.flows.persistence.model.Locality" json:"src_locality,omitempty"`
+	DstLocality       Locality                `protobuf:"varint,42,opt,name=dst_locality,json=dstLocality,proto3,enum=org.opennms.netmgt.flows.persistence.model.Locality" json:"dst_locality,omitempty"`
+	FlowLocality      Locality                `protobuf:"varint,43,opt,name=flow_locality,json=flowLocality,proto3,enum=org.opennms.netmgt.flows.persistence.model.Locality" json:"flow_locality,omitempty"`
+	ClockCorrection   uint64                  `protobuf:"varint,45,opt,name=clock_correction,json=clockCorrection,proto3" json:"clock_correction,omitempty"`
+	Dscp              *wrapperspb.UInt32Value `protobuf:"bytes,46,opt,name=dscp,proto3" json:"dscp,omitempty"`
+	Ecn               *wrapperspb.UInt32Value `protobuf:"bytes,47,opt,name=ecn,proto3" json:"ecn,omitempty"`
+}
+
+func (x *FlowDocument) Reset() {
+	*x = FlowDocument{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_flowdocument_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FlowDocument) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FlowDocument) ProtoMessage() {}
+
+func (x *FlowDocument) ProtoReflect() protoreflect.Message {
+	mi := &file_flowdocument_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FlowDocument.ProtoReflect.Descriptor instead.
+func (*FlowDocument) Descriptor() ([]byte, []int) {
+	return file_flowdocument_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FlowDocument) GetTimestamp() uint64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *FlowDocument) GetNumBytes() *wrapperspb.UInt64Value {
+	if x != nil {
+		return x.NumBytes
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetDirection() Direction {
+	if x != nil {
+		return x.Direction
+	}
+	return Direction_INGRESS
+}
+
+func (x *FlowDocument) GetDstAddress() string {
+	if x != nil {
+		return x.DstAddress
+	}
+	return ""
+}
+
+func (x *FlowDocument) GetDstHostname() string {
+	if x != nil {
+		return x.DstHostname
+	}
+	return ""
+}
+
+func (x *FlowDocument) GetDstAs() *wrapperspb.UInt64Value {
+	if x != nil {
+		return x.DstAs
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetDstMaskLen() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.DstMaskLen
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetDstPort() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.DstPort
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetEngineId() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.EngineId
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetEngineType() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.EngineType
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetDeltaSwitched() *wrapperspb.UInt64Value {
+	if x != nil {
+		return x.DeltaSwitched
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetFirstSwitched() *wrapperspb.UInt64Value {
+	if x != nil {
+		return x.FirstSwitched
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetLastSwitched() *wrapperspb.UInt64Value {
+	if x != nil {
+		return x.LastSwitched
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetNumFlowRecords() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.NumFlowRecords
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetNumPackets() *wrapperspb.UInt64Value {
+	if x != nil {
+		return x.NumPackets
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetFlowSeqNum() *wrapperspb.UInt64Value {
+	if x != nil {
+		return x.FlowSeqNum
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetInputSnmpIfindex() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.InputSnmpIfindex
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetOutputSnmpIfindex() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.OutputSnmpIfindex
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetIpProtocolVersion() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.IpProtocolVersion
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetNextHopAddress() string {
+	if x != nil {
+		return x.NextHopAddress
+	}
+	return ""
+}
+
+func (x *FlowDocument) GetNextHopHostname() string {
+	if x != nil {
+		return x.NextHopHostname
+	}
+	return ""
+}
+
+func (x *FlowDocument) GetProtocol() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.Protocol
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetSamplingAlgorithm() SamplingAlgorithm {
+	if x != nil {
+		return x.SamplingAlgorithm
+	}
+	return SamplingAlgorithm_UNASSIGNED
+}
+
+func (x *FlowDocument) GetSamplingInterval() *wrapperspb.DoubleValue {
+	if x != nil {
+		return x.SamplingInterval
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetSrcAddress() string {
+	if x != nil {
+		return x.SrcAddress
+	}
+	return ""
+}
+
+func (x *FlowDocument) GetSrcHostname() string {
+	if x != nil {
+		return x.SrcHostname
+	}
+	return ""
+}
+
+func (x *FlowDocument) GetSrcAs() *wrapperspb.UInt64Value {
+	if x != nil {
+		return x.SrcAs
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetSrcMaskLen() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.SrcMaskLen
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetSrcPort() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.SrcPort
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetTcpFlags() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.TcpFlags
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetTos() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.Tos
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetNetflowVersion() NetflowVersion {
+	if x != nil {
+		return x.NetflowVersion
+	}
+	return NetflowVersion_V5
+}
+
+func (x *FlowDocument) GetVlan() string {
+	if x != nil {
+		return x.Vlan
+	}
+	return ""
+}
+
+func (x *FlowDocument) GetSrcNode() *NodeInfo {
+	if x != nil {
+		return x.SrcNode
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetExporterNode() *NodeInfo {
+	if x != nil {
+		return x.ExporterNode
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetDstNode() *NodeInfo {
+	if x != nil {
+		return x.DstNode
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetApplication() string {
+	if x != nil {
+		return x.Application
+	}
+	return ""
+}
+
+func (x *FlowDocument) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *FlowDocument) GetLocation() string {
+	if x != nil {
+		return x.Location
+	}
+	return ""
+}
+
+func (x *FlowDocument) GetSrcLocality() Locality {
+	if x != nil {
+		return x.SrcLocality
+	}
+	return Locality_PUBLIC
+}
+
+func (x *FlowDocument) GetDstLocality() Locality {
+	if x != nil {
+		return x.DstLocality
+	}
+	return Locality_PUBLIC
+}
+
+func (x *FlowDocument) GetFlowLocality() Locality {
+	if x != nil {
+		return x.FlowLocality
+	}
+	return Locality_PUBLIC
+}
+
+func (x *FlowDocument) GetClockCorrection() uint64 {
+	if x != nil {
+		return x.ClockCorrection
+	}
+	return 0
+}
+
+func (x *FlowDocument) GetDscp() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.Dscp
+	}
+	return nil
+}
+
+func (x *FlowDocument) GetEcn() *wrapperspb.UInt32Value {
+	if x != nil {
+		return x.Ecn
+	}
+	return nil
+}
+
+var File_flowdocument_proto protoreflect.FileDescriptor
+
+var file_flowdocument_proto_rawDesc = []byte{
+	0x0a, 0x12, 0x66, 0x6c, 0x6f, 0x77, 0x64, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x2a, 0x6f, 0x72, 0x67, 0x2e, 0x6f, 0x70, 0x65, 0x6e, 0x6e, 0x6d,
+	0x73, 0x2e, 0x6e, 0x65, 0x74, 0x6d, 0x67, 0x74, 0x2e, 0x66, 0x6c, 0x6f, 0x77, 0x73, 0x2e, 0x70,
+	0x65, 0x72, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x65, 0x2e, 0x6d, 0x6f, 0x64, 0x65, 0x6c,
+	0x1a, 0x1e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2f, 0x77, 0x72, 0x61, 0x70, 0x70, 0x65, 0x72, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x22, 0x89, 0x01, 0x0a, 0x08, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x25, 0x0a,
+	0x0e, 0x66, 0x6f, 0x72, 0x65, 0x69, 0x67, 0x6e, 0x5f, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x66, 0x6f, 0x72, 0x65, 0x69, 0x67, 0x6e, 0x53, 0x6f,
+	0x75, 0x72, 0x63, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x66, 0x6f, 0x72, 0x65, 0x69, 0x67, 0x6e, 0x5f,
+	0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x66, 0x6f, 0x72, 0x65, 0x69, 0x67,
+	0x6e, 0x49, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x6e, 0x6f, 0x64, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x6e, 0x6f, 0x64, 0x65, 0x49, 0x64, 0x12, 0x1e, 0x0a, 0x0a,
+	0x63, 0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x69, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x0a, 0x63, 0x61, 0x74, 0x65, 0x67, 0x6f, 0x72, 0x69, 0x65, 0x73, 0x22, 0xac, 0x15, 0x0a,
+	0x0c, 0x46, 0x6c, 0x6f, 0x77, 0x44, 0x6f, 0x63, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x1c, 0x0a,
+	0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x39, 0x0a, 0x09, 0x6e,
+	0x75, 0x6d, 0x5f, 0x62, 0x79, 0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x55, 0x49, 0x6e, 0x74, 0x36, 0x34, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x08, 0x6e, 0x75,
+	0x6d, 0x42, 0x79, 0x74, 0x65, 0x73, 0x12, 0x53, 0x0a, 0x09, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x35, 0x2e, 0x6f, 0x72, 0x67, 0x2e,
+	0x6f, 0x70, 0x65, 0x6e, 0x6e, 0x6d, 0x73, 0x2e, 0x6e, 0x65, 0x74, 0x6d, 0x67, 0x74, 0x2e, 0x66,
+	0x6c, 0x6f, 0x77, 0x73, 0x2e, 0x70, 0x65, 0x72, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x65,
+	0x2e, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x2e, 0x44, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x09, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1f, 0x0a, 0x0b, 0x64,
+	0x73, 0x74, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0a, 0x64, 0x73, 0x74, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x21, 0x0a, 0x0c,
+	0x64, 0x73, 0x74, 0x5f, 0x68, 0x6f, 0x73, 0x74, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0b, 0x64, 0x73, 0x74, 0x48, 0x6f, 0x73, 0x74, 0x6e, 0x61, 0x6d, 0x65, 0x12,
+	0x33, 0x0a, 0x06, 0x64, 0x73, 0x74, 0x5f, 0x61, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x36, 0x34, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x05, 0x64,
+	0x73, 0x74, 0x41, 0x73, 0x12, 0x3e, 0x0a, 0x0c, 0x64, 0x73, 0x74, 0x5f, 0x6d, 0x61, 0x73, 0x6b,
+	0x5f, 0x6c, 0x65, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e,
+	0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x0a, 0x64, 0x73, 0x74, 0x4d, 0x61, 0x73,
+	0x6b, 0x4c, 0x65, 0x6e, 0x12, 0x37, 0x0a, 0x08, 0x64, 0x73, 0x74, 0x5f, 0x70, 0x6f, 0x72, 0x74,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33, 0x32, 0x56,
+	0x61, 0x6c, 0x75, 0x65, 0x52, 0x07, 0x64, 0x73, 0x74, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x39, 0x0a,
+	0x09, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x5f, 0x69, 0x64, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x08,
+	0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x49, 0x64, 0x12, 0x3d, 0x0a, 0x0b, 0x65, 0x6e, 0x67, 0x69,
+	0x6e, 0x65, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x55, 0x49, 0x6e, 0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x0a, 0x65, 0x6e, 0x67,
+	0x69, 0x6e, 0x65, 0x54, 0x79, 0x70, 0x65, 0x12, 0x43, 0x0a, 0x0e, 0x64, 0x65, 0x6c, 0x74, 0x61,
+	0x5f, 0x73, 0x77, 0x69, 0x74, 0x63, 0x68, 0x65, 0x64, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x36, 0x34, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x0d, 0x64,
+	0x65, 0x6c, 0x74, 0x61, 0x53, 0x77, 0x69, 0x74, 0x63, 0x68, 0x65, 0x64, 0x12, 0x43, 0x0a, 0x0e,
+	0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x73, 0x77, 0x69, 0x74, 0x63, 0x68, 0x65, 0x64, 0x18, 0x0c,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x36, 0x34, 0x56, 0x61, 0x6c,
+	0x75, 0x65, 0x52, 0x0d, 0x66, 0x69, 0x72, 0x73, 0x74, 0x53, 0x77, 0x69, 0x74, 0x63, 0x68, 0x65,
+	0x64, 0x12, 0x41, 0x0a, 0x0d, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x73, 0x77, 0x69, 0x74, 0x63, 0x68,
+	0x65, 0x64, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x36,
+	0x34, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x0c, 0x6c, 0x61, 0x73, 0x74, 0x53, 0x77, 0x69, 0x74,
+	0x63, 0x68, 0x65, 0x64, 0x12, 0x46, 0x0a, 0x10, 0x6e, 0x75, 0x6d, 0x5f, 0x66, 0x6c, 0x6f, 0x77,
+	0x5f, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x0e, 0x6e, 0x75,
+	0x6d, 0x46, 0x6c, 0x6f, 0x77, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x73, 0x12, 0x3d, 0x0a, 0x0b,
+	0x6e, 0x75, 0x6d, 0x5f, 0x70, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x0f, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x36, 0x34, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52,
+	0x0a, 0x6e, 0x75, 0x6d, 0x50, 0x61, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x12, 0x3e, 0x0a, 0x0c, 0x66,
+	0x6c, 0x6f, 0x77, 0x5f, 0x73, 0x65, 0x71, 0x5f, 0x6e, 0x75, 0x6d, 0x18, 0x10, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x36, 0x34, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52,
+	0x0a, 0x66, 0x6c, 0x6f, 0x77, 0x53, 0x65, 0x71, 0x4e, 0x75, 0x6d, 0x12, 0x4a, 0x0a, 0x12, 0x69,
+	0x6e, 0x70, 0x75, 0x74, 0x5f, 0x73, 0x6e, 0x6d, 0x70, 0x5f, 0x69, 0x66, 0x69, 0x6e, 0x64, 0x65,
+	0x78, 0x18, 0x11, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33, 0x32,
+	0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x10, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x53, 0x6e, 0x6d, 0x70,
+	0x49, 0x66, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x4c, 0x0a, 0x13, 0x6f, 0x75, 0x74, 0x70, 0x75,
+	0x74, 0x5f, 0x73, 0x6e, 0x6d, 0x70, 0x5f, 0x69, 0x66, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x12,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33, 0x32, 0x56, 0x61, 0x6c,
+	0x75, 0x65, 0x52, 0x11, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x53, 0x6e, 0x6d, 0x70, 0x49, 0x66,
+	0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x4c, 0x0a, 0x13, 0x69, 0x70, 0x5f, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x63, 0x6f, 0x6c, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x13, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65,
+	0x52, 0x11, 0x69, 0x70, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x56, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x28, 0x0a, 0x10, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x68, 0x6f, 0x70, 0x5f,
+	0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x14, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x6e,
+	0x65, 0x78, 0x74, 0x48, 0x6f, 0x70, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x2a, 0x0a,
+	0x11, 0x6e, 0x65, 0x78, 0x74, 0x5f, 0x68, 0x6f, 0x70, 0x5f, 0x68, 0x6f, 0x73, 0x74, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x15, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x6e, 0x65, 0x78, 0x74, 0x48, 0x6f,
+	0x70, 0x48, 0x6f, 0x73, 0x74, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x38, 0x0a, 0x08, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x18, 0x16, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49,
+	0x6e, 0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x63, 0x6f, 0x6c, 0x12, 0x6c, 0x0a, 0x12, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x69, 0x6e, 0x67, 0x5f,
+	0x61, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x18, 0x17, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x3d, 0x2e, 0x6f, 0x72, 0x67, 0x2e, 0x6f, 0x70, 0x65, 0x6e, 0x6e, 0x6d, 0x73, 0x2e, 0x6e, 0x65,
+	0x74, 0x6d, 0x67, 0x74, 0x2e, 0x66, 0x6c, 0x6f, 0x77, 0x73, 0x2e, 0x70, 0x65, 0x72, 0x73, 0x69,
+	0x73, 0x74, 0x65, 0x6e, 0x63, 0x65, 0x2e, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x2e, 0x53, 0x61, 0x6d,
+	0x70, 0x6c, 0x69, 0x6e, 0x67, 0x41, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68, 0x6d, 0x52, 0x11,
+	0x73, 0x61, 0x6d, 0x70, 0x6c, 0x69, 0x6e, 0x67, 0x41, 0x6c, 0x67, 0x6f, 0x72, 0x69, 0x74, 0x68,
+	0x6d, 0x12, 0x49, 0x0a, 0x11, 0x73, 0x61, 0x6d, 0x70, 0x6c, 0x69, 0x6e, 0x67, 0x5f, 0x69, 0x6e,
+	0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x18, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44,
+	0x6f, 0x75, 0x62, 0x6c, 0x65, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x10, 0x73, 0x61, 0x6d, 0x70,
+	0x6c, 0x69, 0x6e, 0x67, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x1f, 0x0a, 0x0b,
+	0x73, 0x72, 0x63, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x1a, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0a, 0x73, 0x72, 0x63, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x21, 0x0a,
+	0x0c, 0x73, 0x72, 0x63, 0x5f, 0x68, 0x6f, 0x73, 0x74, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x1b, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0b, 0x73, 0x72, 0x63, 0x48, 0x6f, 0x73, 0x74, 0x6e, 0x61, 0x6d, 0x65,
+	0x12, 0x33, 0x0a, 0x06, 0x73, 0x72, 0x63, 0x5f, 0x61, 0x73, 0x18, 0x1c, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x36, 0x34, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x05,
+	0x73, 0x72, 0x63, 0x41, 0x73, 0x12, 0x3e, 0x0a, 0x0c, 0x73, 0x72, 0x63, 0x5f, 0x6d, 0x61, 0x73,
+	0x6b, 0x5f, 0x6c, 0x65, 0x6e, 0x18, 0x1d, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49,
+	0x6e, 0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x0a, 0x73, 0x72, 0x63, 0x4d, 0x61,
+	0x73, 0x6b, 0x4c, 0x65, 0x6e, 0x12, 0x37, 0x0a, 0x08, 0x73, 0x72, 0x63, 0x5f, 0x70, 0x6f, 0x72,
+	0x74, 0x18, 0x1e, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33, 0x32,
+	0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x07, 0x73, 0x72, 0x63, 0x50, 0x6f, 0x72, 0x74, 0x12, 0x39,
+	0x0a, 0x09, 0x74, 0x63, 0x70, 0x5f, 0x66, 0x6c, 0x61, 0x67, 0x73, 0x18, 0x1f, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33, 0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52,
+	0x08, 0x74, 0x63, 0x70, 0x46, 0x6c, 0x61, 0x67, 0x73, 0x12, 0x2e, 0x0a, 0x03, 0x74, 0x6f, 0x73,
+	0x18, 0x20, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33, 0x32, 0x56,
+	0x61, 0x6c, 0x75, 0x65, 0x52, 0x03, 0x74, 0x6f, 0x73, 0x12, 0x63, 0x0a, 0x0f, 0x6e, 0x65, 0x74,
+	0x66, 0x6c, 0x6f, 0x77, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x21, 0x20, 0x01,
+	0x28, 0x0e, 0x32, 0x3a, 0x2e, 0x6f, 0x72, 0x67, 0x2e, 0x6f, 0x70, 0x65, 0x6e, 0x6e, 0x6d, 0x73,
+	0x2e, 0x6e, 0x65, 0x74, 0x6d, 0x67, 0x74, 0x2e, 0x66, 0x6c, 0x6f, 0x77, 0x73, 0x2e, 0x70, 0x65,
+	0x72, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x65, 0x2e, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x2e,
+	0x4e, 0x65, 0x74, 0x66, 0x6c, 0x6f, 0x77, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x0e,
+	0x6e, 0x65, 0x74, 0x66, 0x6c, 0x6f, 0x77, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x12,
+	0x0a, 0x04, 0x76, 0x6c, 0x61, 0x6e, 0x18, 0x22, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x76, 0x6c,
+	0x61, 0x6e, 0x12, 0x4f, 0x0a, 0x08, 0x73, 0x72, 0x63, 0x5f, 0x6e, 0x6f, 0x64, 0x65, 0x18, 0x23,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x34, 0x2e, 0x6f, 0x72, 0x67, 0x2e, 0x6f, 0x70, 0x65, 0x6e, 0x6e,
+	0x6d, 0x73, 0x2e, 0x6e, 0x65, 0x74, 0x6d, 0x67, 0x74, 0x2e, 0x66, 0x6c, 0x6f, 0x77, 0x73, 0x2e,
+	0x70, 0x65, 0x72, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x65, 0x2e, 0x6d, 0x6f, 0x64, 0x65,
+	0x6c, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x73, 0x72, 0x63, 0x4e,
+	0x6f, 0x64, 0x65, 0x12, 0x59, 0x0a, 0x0d, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x72, 0x5f,
+	0x6e, 0x6f, 0x64, 0x65, 0x18, 0x24, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x34, 0x2e, 0x6f, 0x72, 0x67,
+	0x2e, 0x6f, 0x70, 0x65, 0x6e, 0x6e, 0x6d, 0x73, 0x2e, 0x6e, 0x65, 0x74, 0x6d, 0x67, 0x74, 0x2e,
+	0x66, 0x6c, 0x6f, 0x77, 0x73, 0x2e, 0x70, 0x65, 0x72, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63,
+	0x65, 0x2e, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f,
+	0x52, 0x0c, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x72, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x4f,
+	0x0a, 0x08, 0x64, 0x73, 0x74, 0x5f, 0x6e, 0x6f, 0x64, 0x65, 0x18, 0x25, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x34, 0x2e, 0x6f, 0x72, 0x67, 0x2e, 0x6f, 0x70, 0x65, 0x6e, 0x6e, 0x6d, 0x73, 0x2e, 0x6e,
+	0x65, 0x74, 0x6d, 0x67, 0x74, 0x2e, 0x66, 0x6c, 0x6f, 0x77, 0x73, 0x2e, 0x70, 0x65, 0x72, 0x73,
+	0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x65, 0x2e, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x2e, 0x4e, 0x6f,
+	0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x07, 0x64, 0x73, 0x74, 0x4e, 0x6f, 0x64, 0x65, 0x12,
+	0x20, 0x0a, 0x0b, 0x61, 0x70, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x26,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x61, 0x70, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x18, 0x27, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x68, 0x6f, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x18, 0x28, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6c, 0x6f, 0x63, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x57, 0x0a, 0x0c, 0x73, 0x72, 0x63, 0x5f, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x69, 0x74,
+	0x79, 0x18, 0x29, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x34, 0x2e, 0x6f, 0x72, 0x67, 0x2e, 0x6f, 0x70,
+	0x65, 0x6e, 0x6e, 0x6d, 0x73, 0x2e, 0x6e, 0x65, 0x74, 0x6d, 0x67, 0x74, 0x2e, 0x66, 0x6c, 0x6f,
+	0x77, 0x73, 0x2e, 0x70, 0x65, 0x72, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x65, 0x2e, 0x6d,
+	0x6f, 0x64, 0x65, 0x6c, 0x2e, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x52, 0x0b, 0x73,
+	0x72, 0x63, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x57, 0x0a, 0x0c, 0x64, 0x73,
+	0x74, 0x5f, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x18, 0x2a, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x34, 0x2e, 0x6f, 0x72, 0x67, 0x2e, 0x6f, 0x70, 0x65, 0x6e, 0x6e, 0x6d, 0x73, 0x2e, 0x6e,
+	0x65, 0x74, 0x6d, 0x67, 0x74, 0x2e, 0x66, 0x6c, 0x6f, 0x77, 0x73, 0x2e, 0x70, 0x65, 0x72, 0x73,
+	0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x65, 0x2e, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x2e, 0x4c, 0x6f,
+	0x63, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x52, 0x0b, 0x64, 0x73, 0x74, 0x4c, 0x6f, 0x63, 0x61, 0x6c,
+	0x69, 0x74, 0x79, 0x12, 0x59, 0x0a, 0x0d, 0x66, 0x6c, 0x6f, 0x77, 0x5f, 0x6c, 0x6f, 0x63, 0x61,
+	0x6c, 0x69, 0x74, 0x79, 0x18, 0x2b, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x34, 0x2e, 0x6f, 0x72, 0x67,
+	0x2e, 0x6f, 0x70, 0x65, 0x6e, 0x6e, 0x6d, 0x73, 0x2e, 0x6e, 0x65, 0x74, 0x6d, 0x67, 0x74, 0x2e,
+	0x66, 0x6c, 0x6f, 0x77, 0x73, 0x2e, 0x70, 0x65, 0x72, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63,
+	0x65, 0x2e, 0x6d, 0x6f, 0x64, 0x65, 0x6c, 0x2e, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x69, 0x74, 0x79,
+	0x52, 0x0c, 0x66, 0x6c, 0x6f, 0x77, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x29,
+	0x0a, 0x10, 0x63, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x2d, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f, 0x63, 0x6c, 0x6f, 0x63, 0x6b, 0x43,
+	0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x30, 0x0a, 0x04, 0x64, 0x73, 0x63,
+	0x70, 0x18, 0x2e, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33, 0x32,
+	0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x04, 0x64, 0x73, 0x63, 0x70, 0x12, 0x2e, 0x0a, 0x03, 0x65,
+	0x63, 0x6e, 0x18, 0x2f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x55, 0x49, 0x6e, 0x74, 0x33,
+	0x32, 0x56, 0x61, 0x6c, 0x75, 0x65, 0x52, 0x03, 0x65, 0x63, 0x6e, 0x2a, 0x32, 0x0a, 0x09, 0x44,
+	0x69, 0x72, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x0b, 0x0a, 0x07, 0x49, 0x4e, 0x47, 0x52,
+	0x45, 0x53, 0x53, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x45, 0x47, 0x52, 0x45, 0x53, 0x53, 0x10,
+	0x01, 0x12, 0x0c, 0x0a, 0x07, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0xff, 0x01, 0x2a,
+	0xa6, 0x02, 0x0a, 0x11, 0x53, 0x61, 0x6d, 0x70, 0x6c, 0x69, 0x6e, 0x67, 0x41, 0x6c, 0x67, 0x6f,
+	0x72, 0x69, 0x74, 0x68, 0x6d, 0x12, 0x0e, 0x0a, 0x0a, 0x55, 0x4e, 0x41, 0x53, 0x53, 0x49, 0x47,
+	0x4e, 0x45, 0x44, 0x10, 0x00, 0x12, 0x23, 0x0a, 0x1f, 0x53, 0x59, 0x53, 0x54, 0x45, 0x4d, 0x41,
+	0x54, 0x49, 0x43, 0x5f, 0x43, 0x4f, 0x55, 0x4e, 0x54, 0x5f, 0x42, 0x41, 0x53, 0x45, 0x44, 0x5f,
+	0x53, 0x41, 0x4d, 0x50, 0x4c, 0x49, 0x4e, 0x47, 0x10, 0x01, 0x12, 0x22, 0x0a, 0x1e, 0x53, 0x59,
+	0x53, 0x54, 0x45, 0x4d, 0x41, 0x54, 0x49, 0x43, 0x5f, 0x54, 0x49, 0x4d, 0x45, 0x5f, 0x42, 0x41,
+	0x53, 0x45, 0x44, 0x5f, 0x53, 0x41, 0x4d, 0x50, 0x4c, 0x49, 0x4e, 0x47, 0x10, 0x02, 0x12, 0x1e,
+	0x0a, 0x1a, 0x52, 0x41, 0x4e, 0x44, 0x4f, 0x4d, 0x5f, 0x4e, 0x5f, 0x4f, 0x55, 0x54, 0x5f, 0x4f,
+	0x46, 0x5f, 0x4e, 0x5f, 0x53, 0x41, 0x4d, 0x50, 0x4c, 0x49, 0x4e, 0x47, 0x10, 0x03, 0x12, 0x22,
+	0x0a, 0x1e, 0x55, 0x4e, 0x49, 0x46, 0x4f, 0x52, 0x4d, 0x5f, 0x50, 0x52, 0x4f, 0x42, 0x41, 0x42,
+	0x49, 0x4c, 0x49, 0x53, 0x54, 0x49, 0x43, 0x5f, 0x53, 0x41, 0x4d, 0x50, 0x4c, 0x49, 0x4e, 0x47,
+	0x10, 0x04, 0x12, 0x1c, 0x0a, 0x18, 0x50, 0x52, 0x4f, 0x50, 0x45, 0x52, 0x54, 0x59, 0x5f, 0x4d,
+	0x41, 0x54, 0x43, 0x48, 0x5f, 0x46, 0x49, 0x4c, 0x54, 0x45, 0x52, 0x49, 0x4e, 0x47, 0x10, 0x05,
+	0x12, 0x18, 0x0a, 0x14, 0x48, 0x41, 0x53, 0x48, 0x5f, 0x42, 0x41, 0x53, 0x45, 0x44, 0x5f, 0x46,
+	0x49, 0x4c, 0x54, 0x45, 0x52, 0x49, 0x4e, 0x47, 0x10, 0x06, 0x12, 0x3c, 0x0a, 0x38, 0x46, 0x4c,
+	0x4f, 0x57, 0x5f, 0x53, 0x54, 0x41, 0x54, 0x45, 0x5f, 0x44, 0x45, 0x50, 0x45, 0x4e, 0x44, 0x45,
+	0x4e, 0x54, 0x5f, 0x49, 0x4e, 0x54, 0x45, 0x52, 0x4d, 0x45, 0x44, 0x49, 0x41, 0x54, 0x45, 0x5f,
+	0x46, 0x4c, 0x4f, 0x57, 0x5f, 0x53, 0x45, 0x4c, 0x45, 0x43, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x50,
+	0x52, 0x4f, 0x43, 0x45, 0x53, 0x53, 0x10, 0x07, 0x2a, 0x36, 0x0a, 0x0e, 0x4e, 0x65, 0x74, 0x66,
+	0x6c, 0x6f, 0x77, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x06, 0x0a, 0x02, 0x56, 0x35,
+	0x10, 0x00, 0x12, 0x06, 0x0a, 0x02, 0x56, 0x39, 0x10, 0x01, 0x12, 0x09, 0x0a, 0x05, 0x49, 0x50,
+	0x46, 0x49, 0x58, 0x10, 0x02, 0x12, 0x09, 0x0a, 0x05, 0x53, 0x46, 0x4c, 0x4f, 0x57, 0x10, 0x03,
+	0x2a, 0x23, 0x0a, 0x08, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x0a, 0x0a, 0x06,
+	0x50, 0x55, 0x42, 0x4c, 0x49, 0x43, 0x10, 0x00, 0x12, 0x0b, 0x0a, 0x07, 0x50, 0x52, 0x49, 0x56,
+	0x41, 0x54, 0x45, 0x10, 0x01, 0x42, 0x09, 0x5a, 0x07, 0x2e, 0x3b, 0x66, 0x6c, 0x6f, 0x77, 0x73,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_flowdocument_proto_rawDescOnce sync.Once
+	file_flowdocument_proto_rawDescData = file_flowdocument_proto_rawDesc
+)
+
+func file_flowdocument_proto_rawDescGZIP() []byte {
+	file_flowdocument_proto_rawDescOnce.Do(func() {
+		file_flowdocument_proto_rawDescData = protoimpl.X.CompressGZIP(file_flowdocument_proto_rawDescData)
+	})
+	return file_flowdocument_proto_rawDescData
+}
+
+var file_flowdocument_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_flowdocument_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_flowdocument_proto_goTypes = []interface{}{
+	(Direction)(0),                 // 0: org.opennms.netmgt.flows.persistence.model.Direction
+	(SamplingAlgorithm)(0),         // 1: org.opennms.netmgt.flows.persistence.model.SamplingAlgorithm
+	(NetflowVersion)(0),            // 2: org.opennms.netmgt.flows.persistence.model.NetflowVersion
+	(Locality)(0),                  // 3: org.opennms.netmgt.flows.persistence.model.Locality
+	(*NodeInfo)(nil),               // 4: org.opennms.netmgt.flows.persistence.model.NodeInfo
+	(*FlowDocument)(nil),           // 5: org.opennms.netmgt.flows.persistence.model.FlowDocument
+	(*wrapperspb.UInt64Value)(nil), // 6: google.protobuf.UInt64Value
+	(*wrapperspb.UInt32Value)(nil), // 7: google.protobuf.UInt32Value
+	(*wrapperspb.DoubleValue)(nil), // 8: google.protobuf.DoubleValue
+}
+var file_flowdocument_proto_depIdxs = []int32{
+	6,  // 0: org.opennms.netmgt.flows.persistence.model.FlowDocument.num_bytes:type_name -> google.protobuf.UInt64Value
+	0,  // 1: org.opennms.netmgt.flows.persistence.model.FlowDocument.direction:type_name -> org.opennms.netmgt.flows.persistence.model.Direction
+	6,  // 2: org.opennms.netmgt.flows.persistence.model.FlowDocument.dst_as:type_name -> google.protobuf.UInt64Value
+	7,  // 3: org.opennms.netmgt.flows.persistence.model.FlowDocument.dst_mask_len:type_name -> google.protobuf.UInt32Value
+	7,  // 4: org.opennms.netmgt.flows.persistence.model.FlowDocument.dst_port:type_name -> google.protobuf.UInt32Value
+	7,  // 5: org.opennms.netmgt.flows.persistence.model.FlowDocument.engine_id:type_name -> google.protobuf.UInt32Value
+	7,  // 6: org.opennms.netmgt.flows.persistence.model.FlowDocument.engine_type:type_name -> google.protobuf.UInt32Value
+	6,  // 7: org.opennms.netmgt.flows.persistence.model.FlowDocument.delta_switched:type_name -> google.protobuf.UInt64Value
+	6,  // 8: org.opennms.netmgt.flows.persistence.model.FlowDocument.first_switched:type_name -> google.protobuf.UInt64Value
+	6,  // 9: org.opennms.netmgt.flows.persistence.model.FlowDocument.last_switched:type_name -> google.protobuf.UInt64Value
+	7,  // 10: org.opennms.netmgt.flows.persistence.model.FlowDocument.num_flow_records:type_name -> google.protobuf.UInt32Value
+	6,  // 11: org.opennms.netmgt.flows.persistence.model.FlowDocument.num_packets:type_name -> google.protobuf.UInt64Value
+	6,  // 12: org.opennms.netmgt.flows.persistence.model.FlowDocument.flow_seq_num:type_name -> google.protobuf.UInt64Value
+	7,  // 13: org.opennms.netmgt.flows.persistence.model.FlowDocument.input_snmp_ifindex:type_name -> google.protobuf.UInt32Value
+	7,  // 14: org.opennms.netmgt.flows.persistence.model.FlowDocument.output_snmp_ifindex:type_name -> google.protobuf.UInt32Value
+	7,  // 15: org.opennms.netmgt.flows.persistence.model.FlowDocument.ip_protocol_version:type_name -> google.protobuf.UInt32Value
+	7,  // 16: org.opennms.netmgt.flows.persistence.model.FlowDocument.protocol:type_name -> google.protobuf.UInt32Value
+	1,  // 17: org.opennms.netmgt.flows.persistence.model.FlowDocument.sampling_algorithm:type_name -> org.opennms.netmgt.flows.persistence.model.SamplingAlgorithm
+	8,  // 18: org.opennms.netmgt.flows.persistence.model.FlowDocument.sampling_interval:type_name -> google.protobuf.DoubleValue
+	6,  // 19: org.opennms.netmgt.flows.persistence.model.FlowDocument.src_as:type_name -> google.protobuf.UInt64Value
+	7,  // 20: org.opennms.netmgt.flows.persistence.model.FlowDocument.src_mask_len:type_name -> google.protobuf.UInt32Value
+	7,  // 21: org.opennms.netmgt.flows.persistence.model.FlowDocument.src_port:type_name -> google.protobuf.UInt32Value
+	7,  // 22: org.opennms.netmgt.flows.persistence.model.FlowDocument.tcp_flags:type_name -> google.protobuf.UInt32Value
+	7,  // 23: org.opennms.netmgt.flows.persistence.model.FlowDocument.tos:type_name -> google.protobuf.UInt32Value
+	2,  // 24: org.opennms.netmgt.flows.persistence.model.FlowDocument.netflow_version:type_name -> org.opennms.netmgt.flows.persistence.model.NetflowVersion
+	4,  // 25: org.opennms.netmgt.flows.persistence.model.FlowDocument.src_node:type_name -> org.opennms.netmgt.flows.persistence.model.NodeInfo
+	4,  // 26: org.opennms.netmgt.flows.persistence.model.FlowDocument.exporter_node:type_name -> org.opennms.netmgt.flows.persistence.model.NodeInfo
+	4,  // 27: org.opennms.netmgt.flows.persistence.model.FlowDocument.dst_node:type_name -> org.opennms.netmgt.flows.persistence.model.NodeInfo
+	3,  // 28: org.opennms.netmgt.flows.persistence.model.FlowDocument.src_locality:type_name -> org.opennms.netmgt.flows.persistence.model.Locality
+	3,  // 29: org.opennms.netmgt.flows.persistence.model.FlowDocument.dst_locality:type_name -> org.opennms.netmgt.flows.persistence.model.Locality
+	3,  // 30: org.opennms.netmgt.flows.persistence.model.FlowDocument.flow_locality:type_name -> org.opennms.netmgt.flows.persistence.model.Locality
+	7,  // 31: org.opennms.netmgt.flows.persistence.model.FlowDocument.dscp:type_name -> google.protobuf.UInt32Value
+	7,  // 32: org.opennms.netmgt.flows.persistence.model.FlowDocument.ecn:type_name -> google.protobuf.UInt32Value
+	33, // [33:33] is the sub-list for method output_type
+	33, // [33:33] is the sub-list for method input_type
+	33, // [33:33] is the sub-list for extension type_name
+	33, // [33:33] is the sub-list for extension extendee
+	0,  // [0:33] is the sub-list for field type_name
+}
+
+func init() { file_flowdocument_proto_init() }
+func file_flowdocument_proto_init() {
+	if File_flowdocument_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_flowdocument_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NodeInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_flowdocument_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FlowDocument); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_flowdocument_proto_rawDesc,
+			NumEnums:      4,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_flowdocument_proto_goTypes,
+		DependencyIndexes: file_flowdocument_proto_depIdxs,
+		EnumInfos:         file_flowdocument_proto_enumTypes,
+		MessageInfos:      file_flowdocument_proto_msgTypes,
+	}.Build()
+	File_flowdocument_proto = out.File
+	file_flowdocument_proto_rawDesc = nil
+	file_flowdocument_proto_goTypes = nil
+	file_flowdocument_proto_depIdxs = nil
+}
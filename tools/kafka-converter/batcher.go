@@ -0,0 +1,128 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Maximum number of batches waiting to be sent; when the queue is full, new batches are dropped so the consumer is never blocked.
+const batchQueueSize = 10
+
+// Batcher accumulates items and sends them in batches from a background routine, when a batch is full or periodically.
+type Batcher struct {
+	Name          string // Destination of the batches, for the logs
+	BatchSize     int    // Zero means no limit
+	FlushInterval time.Duration
+	Send          func(items []interface{}) error
+	mutex         sync.Mutex
+	pending       []interface{}
+	queue         chan []interface{}
+	done          chan struct{}
+	wg            sync.WaitGroup
+}
+
+// NewBatcher creates a batcher that uses the given function to send the batches.
+func NewBatcher(name string, batchSize int, flushInterval time.Duration, send func(items []interface{}) error) *Batcher {
+	return &Batcher{
+		Name:          name,
+		BatchSize:     batchSize,
+		FlushInterval: flushInterval,
+		Send:          send,
+		queue:         make(chan []interface{}, batchQueueSize),
+	}
+}
+
+// Start starts the background routine that sends the queued batches, and flushes the pending items periodically.
+func (b *Batcher) Start() {
+	b.done = make(chan struct{})
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		ticker := time.NewTicker(b.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case items := <-b.queue:
+				b.sendBatch(items)
+			case <-ticker.C:
+				b.Flush()
+			case <-b.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background routine and sends the queued and pending items.
+func (b *Batcher) Stop() {
+	if b.done != nil {
+		close(b.done)
+		b.wg.Wait()
+	}
+	for len(b.queue) > 0 {
+		b.sendBatch(<-b.queue)
+	}
+	b.mutex.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.mutex.Unlock()
+	for _, items := range b.batches(pending) {
+		b.sendBatch(items)
+	}
+}
+
+// Add appends the given items to the pending ones, queuing the full batches.
+func (b *Batcher) Add(items ...interface{}) {
+	b.mutex.Lock()
+	b.pending = append(b.pending, items...)
+	var full []interface{}
+	if b.BatchSize > 0 && len(b.pending) >= b.BatchSize {
+		size := len(b.pending) / b.BatchSize * b.BatchSize
+		full = b.pending[:size]
+		b.pending = append([]interface{}(nil), b.pending[size:]...)
+	}
+	b.mutex.Unlock()
+	for _, batch := range b.batches(full) {
+		b.enqueue(batch)
+	}
+}
+
+// Flush queues the pending items in batches, to be sent by the background routine.
+func (b *Batcher) Flush() {
+	b.mutex.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.mutex.Unlock()
+	for _, batch := range b.batches(pending) {
+		b.enqueue(batch)
+	}
+}
+
+// batches splits the given items in batches of up to the batch size.
+func (b *Batcher) batches(items []interface{}) [][]interface{} {
+	result := make([][]interface{}, 0)
+	for len(items) > 0 {
+		size := b.BatchSize
+		if size <= 0 || size > len(items) {
+			size = len(items)
+		}
+		result = append(result, items[:size])
+		items = items[size:]
+	}
+	return result
+}
+
+func (b *Batcher) enqueue(items []interface{}) {
+	select {
+	case b.queue <- items:
+	default:
+		log.Printf("%s queue is full, dropping %d items\n", b.Name, len(items))
+	}
+}
+
+func (b *Batcher) sendBatch(items []interface{}) {
+	if err := b.Send(items); err != nil {
+		log.Printf("cannot send %d items to %s: %v\n", len(items), b.Name, err)
+	}
+}
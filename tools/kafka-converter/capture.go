@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// CaptureHeader represents a header of a captured Kafka record.
+type CaptureHeader struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// CaptureRecord represents a raw Kafka record in a capture file, where each line is a JSON record.
+// The key and the value are encoded as base64, and a null value represents a tombstone.
+type CaptureRecord struct {
+	Topic     string          `json:"topic"`
+	Partition int32           `json:"partition"`
+	Offset    int64           `json:"offset"`
+	Timestamp int64           `json:"timestamp"` // In milliseconds
+	Key       []byte          `json:"key,omitempty"`
+	Value     []byte          `json:"value"`
+	Headers   []CaptureHeader `json:"headers,omitempty"`
+}
+
+func newCaptureRecord(msg *kafka.Message) *CaptureRecord {
+	record := &CaptureRecord{
+		Topic:     *msg.TopicPartition.Topic,
+		Partition: msg.TopicPartition.Partition,
+		Offset:    int64(msg.TopicPartition.Offset),
+		Key:       msg.Key,
+		Value:     msg.Value,
+	}
+	if !msg.Timestamp.IsZero() {
+		record.Timestamp = msg.Timestamp.UnixNano() / int64(time.Millisecond)
+	}
+	for _, h := range msg.Headers {
+		record.Headers = append(record.Headers, CaptureHeader{Key: h.Key, Value: h.Value})
+	}
+	return record
+}
+
+// Message returns the Kafka message represented by the record.
+func (r *CaptureRecord) Message() *kafka.Message {
+	topic := r.Topic
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: r.Partition, Offset: kafka.Offset(r.Offset)},
+		Key:            r.Key,
+		Value:          r.Value,
+	}
+	if r.Timestamp > 0 {
+		msg.Timestamp = time.Unix(0, r.Timestamp*int64(time.Millisecond))
+	}
+	for _, h := range r.Headers {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: h.Key, Value: h.Value})
+	}
+	return msg
+}
+
+// readCapture reads the records of a capture file, calling a given function for each of them.
+func readCapture(r io.Reader, handler func(msg *kafka.Message)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		record := &CaptureRecord{}
+		if err := json.Unmarshal(scanner.Bytes(), record); err != nil {
+			return fmt.Errorf("invalid capture record on line %d: %v", line, err)
+		}
+		handler(record.Message())
+	}
+	return scanner.Err()
+}
+
+// captureOffsets returns the offsets of the given partitions for a given position: an absolute offset when it is zero or positive,
+// the offset of the first message at or after a given time when it is set, or the given default offset otherwise.
+func captureOffsets(consumer *kafka.Consumer, partitions []kafka.TopicPartition, offset int64, ts time.Time, fallback kafka.Offset) ([]kafka.TopicPartition, error) {
+	result := make([]kafka.TopicPartition, len(partitions))
+	copy(result, partitions)
+	switch {
+	case offset >= 0:
+		for i := range result {
+			result[i].Offset = kafka.Offset(offset)
+		}
+	case !ts.IsZero():
+		for i := range result {
+			result[i].Offset = kafka.Offset(ts.UnixNano() / int64(time.Millisecond))
+		}
+		times, err := consumer.OffsetsForTimes(result, 10000)
+		if err != nil {
+			return nil, err
+		}
+		copy(result, times)
+	default:
+		for i := range result {
+			result[i].Offset = fallback
+		}
+	}
+	// Resolve the logical offsets (i.e. no message after a given time), as the reader needs absolute ones to stop
+	for i, tp := range result {
+		if tp.Offset >= 0 {
+			continue
+		}
+		low, high, err := consumer.QueryWatermarkOffsets(*tp.Topic, tp.Partition, 10000)
+		if err != nil {
+			return nil, err
+		}
+		if tp.Offset == kafka.OffsetBeginning {
+			result[i].Offset = kafka.Offset(low)
+		} else {
+			result[i].Offset = kafka.Offset(high)
+		}
+	}
+	return result, nil
+}
+
+func parseCaptureTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	ts, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return ts, fmt.Errorf("invalid time %s; expected RFC3339 (i.e. 2020-09-13T12:26:40Z)", value)
+	}
+	return ts, nil
+}
+
+// captureCommand dumps a range of a topic to a capture file, to reproduce issues without the broker.
+func captureCommand(args []string) error {
+	cli := &KafkaClient{}
+	fs := flag.NewFlagSet("capture", flag.ExitOnError)
+	fs.StringVar(&cli.Bootstrap, "bootstrap", "localhost:9092", "kafka bootstrap server")
+	fs.StringVar(&cli.ConsumerSettings, "consumer-params", "", "optional kafka consumer parameters as a CSV of Key-Value pairs")
+	topic := fs.String("topic", "", "kafka topic to capture")
+	startOffset := fs.Int64("start-offset", -1, "optional offset to start from on each partition (defaults to the beginning)")
+	endOffset := fs.Int64("end-offset", -1, "optional offset to stop at on each partition, exclusive (defaults to the end)")
+	startTime := fs.String("start-time", "", "optional RFC3339 time to start from; ignored when start-offset is specified")
+	endTime := fs.String("end-time", "", "optional RFC3339 time to stop at, exclusive; ignored when end-offset is specified")
+	output := fs.String("output", "", "optional capture file (defaults to the standard output)")
+	fs.Parse(args)
+	if *topic == "" {
+		return fmt.Errorf("topic cannot be empty")
+	}
+	from, err := parseCaptureTime(*startTime)
+	if err != nil {
+		return err
+	}
+	until, err := parseCaptureTime(*endTime)
+	if err != nil {
+		return err
+	}
+
+	config := cli.getKafkaConfig(cli.ConsumerSettings)
+	config.SetKey("group.id", "kafka-converter-capture")
+	lookup, err := kafka.NewConsumer(config)
+	if err != nil {
+		return fmt.Errorf("could not create consumer: %v", err)
+	}
+	defer lookup.Close()
+
+	w := os.Stdout
+	if *output != "" {
+		if w, err = os.Create(*output); err != nil {
+			return fmt.Errorf("cannot create %s: %v", *output, err)
+		}
+		defer w.Close()
+	}
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+	count := 0
+	var writeErr error
+	reader := &TopicReader{
+		Topic: *topic,
+		Handler: func(msg *kafka.Message) {
+			if writeErr == nil {
+				writeErr = encoder.Encode(newCaptureRecord(msg))
+				count++
+			}
+		},
+		From: func(partitions []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+			return captureOffsets(lookup, partitions, *startOffset, from, kafka.OffsetBeginning)
+		},
+		Until: func(partitions []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+			return captureOffsets(lookup, partitions, *endOffset, until, kafka.OffsetEnd)
+		},
+	}
+	if err := reader.Start(config); err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return fmt.Errorf("cannot write capture: %v", writeErr)
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("cannot write capture: %v", err)
+	}
+	log.Printf("%d messages captured from %s\n", count, *topic)
+	return nil
+}
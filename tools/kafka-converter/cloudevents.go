@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/flows"
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/proto"
+)
+
+// Valid CloudEvents modes, based on the Kafka Protocol Binding for CloudEvents.
+const (
+	structuredMode = "structured"
+	binaryMode     = "binary"
+)
+
+const (
+	cloudEventsVersion     = "1.0"
+	cloudEventsTypePrefix  = "org.opennms."
+	cloudEventsContentType = "application/cloudevents+json"
+	jsonContentType        = "application/json"
+)
+
+// Suffixes for the IDs of the events derived from a source message along with its own event, to keep the source and ID unique.
+var cloudEventsIDSuffixes = map[string]string{
+	enhancedAlarmKind: "enhanced",
+}
+
+// CloudEvent represents the structured content mode of a CloudEvent with JSON data.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            string          `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// newCloudEvent builds a CloudEvent for a converted message.
+func newCloudEvent(msg *kafka.Message, kind string, data proto.Message, jsonBytes []byte) *CloudEvent {
+	tp := msg.TopicPartition
+	event := &CloudEvent{
+		SpecVersion:     cloudEventsVersion,
+		ID:              fmt.Sprintf("%s-%d-%d", *tp.Topic, tp.Partition, tp.Offset),
+		Source:          *tp.Topic,
+		Type:            cloudEventsTypePrefix + kind,
+		Subject:         eventSubject(data),
+		DataContentType: jsonContentType,
+		Data:            jsonBytes,
+	}
+	if suffix, ok := cloudEventsIDSuffixes[kind]; ok {
+		event.ID += "-" + suffix
+	}
+	if ts := eventTime(data); ts > 0 {
+		event.Time = time.Unix(0, ts*int64(time.Millisecond)).UTC().Format(time.RFC3339Nano)
+	} else if !msg.Timestamp.IsZero() {
+		event.Time = msg.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	return event
+}
+
+// Headers returns the Kafka headers for the binary content mode.
+func (e *CloudEvent) Headers() []kafka.Header {
+	headers := []kafka.Header{
+		{Key: "ce_specversion", Value: []byte(e.SpecVersion)},
+		{Key: "ce_id", Value: []byte(e.ID)},
+		{Key: "ce_source", Value: []byte(e.Source)},
+		{Key: "ce_type", Value: []byte(e.Type)},
+		{Key: "content-type", Value: []byte(e.DataContentType)},
+	}
+	if e.Subject != "" {
+		headers = append(headers, kafka.Header{Key: "ce_subject", Value: []byte(e.Subject)})
+	}
+	if e.Time != "" {
+		headers = append(headers, kafka.Header{Key: "ce_time", Value: []byte(e.Time)})
+	}
+	return headers
+}
+
+// nodeSubject returns the node criteria as foreign-source:foreign-id if available, or the node ID otherwise.
+func nodeSubject(id uint64, foreignSource, foreignID string) string {
+	if foreignSource != "" && foreignID != "" {
+		return foreignSource + ":" + foreignID
+	}
+	if id > 0 {
+		return fmt.Sprintf("%d", id)
+	}
+	return ""
+}
+
+func criteriaSubject(c *producer.NodeCriteria) string {
+	if c == nil {
+		return ""
+	}
+	return nodeSubject(c.Id, c.ForeignSource, c.ForeignId)
+}
+
+// eventSubject returns the subject of a given message.
+func eventSubject(data proto.Message) string {
+	switch msg := data.(type) {
+	case *producer.Alarm:
+		return msg.ReductionKey
+	case *producer.Event:
+		return criteriaSubject(msg.NodeCriteria)
+	case *producer.Node:
+		return nodeSubject(msg.Id, msg.ForeignSource, msg.ForeignId)
+	case *producer.TopologyEdge:
+		if msg.Ref != nil {
+			return msg.Ref.Id
+		}
+	case *producer.AlarmFeedback:
+		return msg.SituationKey
+	case *flows.FlowDocument:
+		if n := msg.ExporterNode; n != nil {
+			return nodeSubject(uint64(n.NodeId), n.ForeignSource, n.ForeignId)
+		}
+	}
+	return ""
+}
+
+// eventTime returns the timestamp of a given message in milliseconds, or zero if it doesn't have one.
+func eventTime(data proto.Message) int64 {
+	switch msg := data.(type) {
+	case *producer.Alarm:
+		return int64(msg.LastEventTime)
+	case *producer.Event:
+		return int64(msg.Time)
+	case *producer.Node:
+		return int64(msg.CreateTime)
+	case *producer.CollectionSet:
+		return msg.Timestamp
+	case *producer.AlarmFeedback:
+		return int64(msg.Timestamp)
+	case *flows.FlowDocument:
+		return int64(msg.Timestamp)
+	}
+	return 0
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"gotest.tools/assert"
+)
+
+func TestCloudEvents(t *testing.T) {
+	topic := "OpenNMS-alarms"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 42},
+		Timestamp:      time.Unix(1600000001, 0),
+	}
+	alarm := &producer.Alarm{Id: 1, ReductionKey: "uei.opennms.org/nodes/nodeDown::1", LastEventTime: 1600000000000}
+	data := []byte(`{"id":1}`)
+
+	// Structured mode
+	cli := &KafkaClient{CloudEvents: structuredMode}
+	value, headers, err := cli.wrap(msg, alarmKind, alarm, data)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []kafka.Header{{Key: "content-type", Value: []byte(cloudEventsContentType)}}, headers)
+	event := &CloudEvent{}
+	assert.NilError(t, json.Unmarshal(value, event))
+	assert.DeepEqual(t, &CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              "OpenNMS-alarms-2-42",
+		Source:          "OpenNMS-alarms",
+		Type:            "org.opennms.alarm",
+		Subject:         "uei.opennms.org/nodes/nodeDown::1",
+		Time:            "2020-09-13T12:26:40Z",
+		DataContentType: jsonContentType,
+		Data:            json.RawMessage(data),
+	}, event)
+
+	// Binary mode
+	cli.CloudEvents = binaryMode
+	value, headers, err = cli.wrap(msg, alarmKind, alarm, data)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, data, value)
+	assert.DeepEqual(t, []kafka.Header{
+		{Key: "ce_specversion", Value: []byte("1.0")},
+		{Key: "ce_id", Value: []byte("OpenNMS-alarms-2-42")},
+		{Key: "ce_source", Value: []byte("OpenNMS-alarms")},
+		{Key: "ce_type", Value: []byte("org.opennms.alarm")},
+		{Key: "content-type", Value: []byte(jsonContentType)},
+		{Key: "ce_subject", Value: []byte("uei.opennms.org/nodes/nodeDown::1")},
+		{Key: "ce_time", Value: []byte("2020-09-13T12:26:40Z")},
+	}, headers)
+
+	// The enhanced alarm has a different ID than the alarm it was derived from
+	_, headers, err = cli.wrap(msg, enhancedAlarmKind, alarm, data)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, kafka.Header{Key: "ce_id", Value: []byte("OpenNMS-alarms-2-42-enhanced")}, headers[1])
+	assert.DeepEqual(t, kafka.Header{Key: "ce_type", Value: []byte("org.opennms.alarm.enhanced")}, headers[3])
+
+	// The record timestamp is used when the message doesn't have one, and there is no subject
+	_, headers, err = cli.wrap(msg, metricKind, &producer.CollectionSet{}, data)
+	assert.NilError(t, err)
+	assert.Equal(t, 6, len(headers))
+	assert.DeepEqual(t, kafka.Header{Key: "ce_time", Value: []byte("2020-09-13T12:26:41Z")}, headers[5])
+
+	// Disabled
+	cli.CloudEvents = ""
+	value, headers, err = cli.wrap(msg, alarmKind, alarm, data)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, data, value)
+	assert.Assert(t, headers == nil)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/agalue/kafka-converter/api/producer"
+)
+
+// ResourceInfo represents the resolved identity of a CollectionSetResource.
+type ResourceInfo struct {
+	NodeID        int64  `json:"node_id,omitempty"`
+	ForeignSource string `json:"foreign_source,omitempty"`
+	ForeignID     string `json:"foreign_id,omitempty"`
+	NodeLabel     string `json:"node_label,omitempty"`
+	Location      string `json:"location,omitempty"`
+	ResourceType  string `json:"resource_type"`
+	Instance      string `json:"instance,omitempty"`
+}
+
+// Labels returns the non-empty identity fields as a map.
+func (r ResourceInfo) Labels() map[string]string {
+	labels := make(map[string]string)
+	add := func(key, value string) {
+		if value != "" {
+			labels[key] = value
+		}
+	}
+	if r.NodeID > 0 {
+		add("node_id", strconv.FormatInt(r.NodeID, 10))
+	}
+	add("foreign_source", r.ForeignSource)
+	add("foreign_id", r.ForeignID)
+	add("node_label", r.NodeLabel)
+	add("location", r.Location)
+	add("resource_type", r.ResourceType)
+	add("instance", r.Instance)
+	return labels
+}
+
+// ID returns a stable identifier for the resource, based on the node criteria, the resource type and the instance.
+func (r ResourceInfo) ID() string {
+	parts := make([]string, 0, 4)
+	if r.ForeignSource != "" && r.ForeignID != "" {
+		parts = append(parts, r.ForeignSource+":"+r.ForeignID)
+	} else if r.NodeID > 0 {
+		parts = append(parts, strconv.FormatInt(r.NodeID, 10))
+	}
+	parts = append(parts, r.ResourceType)
+	if r.ResourceType == "responseTime" {
+		parts = append(parts, r.Location)
+	}
+	if r.Instance != "" {
+		parts = append(parts, r.Instance)
+	}
+	return strings.Join(parts, "/")
+}
+
+// resolveResource extracts the identity of a given CollectionSetResource.
+func resolveResource(r *producer.CollectionSetResource) ResourceInfo {
+	info := ResourceInfo{}
+	setNode := func(n *producer.NodeLevelResource) {
+		if n != nil {
+			info.NodeID = n.NodeId
+			info.ForeignSource = n.ForeignSource
+			info.ForeignID = n.ForeignId
+			info.NodeLabel = n.NodeLabel
+			info.Location = n.Location
+		}
+	}
+	switch res := r.Resource.(type) {
+	case *producer.CollectionSetResource_Node:
+		info.ResourceType = "node"
+		setNode(res.Node)
+	case *producer.CollectionSetResource_Interface:
+		info.ResourceType = "interface"
+		if res.Interface != nil {
+			setNode(res.Interface.Node)
+			info.Instance = res.Interface.Instance
+		}
+	case *producer.CollectionSetResource_Generic:
+		info.ResourceType = "generic"
+		if res.Generic != nil {
+			setNode(res.Generic.Node)
+			info.ResourceType = res.Generic.Type
+			info.Instance = res.Generic.Instance
+		}
+	case *producer.CollectionSetResource_Response:
+		info.ResourceType = "responseTime"
+		if res.Response != nil {
+			info.Instance = res.Response.Instance
+			info.Location = res.Response.Location
+		}
+	default:
+		info.ResourceType = "unknown"
+	}
+	return info
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Headers added to the messages sent to the dead-letter topic.
+const (
+	dlqErrorHeader     = "dlq.error"
+	dlqTopicHeader     = "dlq.source.topic"
+	dlqPartitionHeader = "dlq.source.partition"
+	dlqOffsetHeader    = "dlq.source.offset"
+	dlqKindHeader      = "dlq.kind"
+)
+
+// The kind of the dead-letter messages with records that could not be delivered, instead of source messages.
+const recordKind = "record"
+
+// The default consumer group of the replay command, followed by the message kind when replaying only one kind.
+const replayGroupID = "kafka-converter-replay"
+
+// deadLetter sends the original message to the dead-letter topic, if configured, with the reason of the failure.
+func (cli *KafkaClient) deadLetter(msg *kafka.Message, kind string, err error) {
+	if cli.DeadLetterTopic == "" {
+		log.Printf("discarding %s message from %s: %v\n", kind, msg.TopicPartition, err)
+		return
+	}
+	tp := msg.TopicPartition
+	headers := append(append([]kafka.Header{}, msg.Headers...),
+		kafka.Header{Key: dlqErrorHeader, Value: []byte(err.Error())},
+		kafka.Header{Key: dlqTopicHeader, Value: []byte(*tp.Topic)},
+		kafka.Header{Key: dlqPartitionHeader, Value: []byte(strconv.Itoa(int(tp.Partition)))},
+		kafka.Header{Key: dlqOffsetHeader, Value: []byte(strconv.FormatInt(int64(tp.Offset), 10))},
+		kafka.Header{Key: dlqKindHeader, Value: []byte(kind)},
+	)
+	cli.produce(msg, cli.DeadLetterTopic, msg.Key, msg.Value, headers...)
+	log.Printf("%s message from %s sent to %s: %v\n", kind, tp, cli.DeadLetterTopic, err)
+}
+
+// replayCommand re-drives the messages from a dead-letter topic to their source topics.
+func replayCommand(args []string) error {
+	cli := &KafkaClient{}
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fs.StringVar(&cli.Bootstrap, "bootstrap", "localhost:9092", "kafka bootstrap server")
+	fs.StringVar(&cli.DeadLetterTopic, "dead-letter-topic", "", "kafka dead-letter topic to replay")
+	fs.StringVar(&cli.GroupID, "group-id", "", "kafka consumer group ID, to avoid replaying the same messages twice (defaults to "+replayGroupID+", plus the message kind when specified)")
+	fs.StringVar(&cli.ProducerSettings, "producer-params", "", "optional kafka producer parameters as a CSV of Key-Value pairs")
+	fs.StringVar(&cli.ConsumerSettings, "consumer-params", "", "optional kafka consumer parameters as a CSV of Key-Value pairs")
+	destTopic := fs.String("dest-topic", "", "optional kafka topic for the replayed messages (defaults to the original source topic)")
+	kind := fs.String("message-kind", "", "optional message kind, or "+recordKind+" for the records that could not be delivered; when specified, only messages of this kind are replayed")
+	timeout := fs.Duration("timeout", time.Minute, "maximum time to wait for the partition assignment or for new messages")
+	fs.Parse(args)
+	if cli.DeadLetterTopic == "" {
+		return fmt.Errorf("dead-letter topic cannot be empty")
+	}
+	if cli.GroupID == "" {
+		// The offsets of the skipped messages are committed, so each kind needs its own group to replay them later
+		cli.GroupID = replayGroupID
+		if *kind != "" {
+			cli.GroupID += "-" + *kind
+		}
+	}
+
+	var err error
+	if cli.producer, err = kafka.NewProducer(cli.getKafkaConfig(cli.ProducerSettings)); err != nil {
+		return fmt.Errorf("could not create producer: %v", err)
+	}
+	defer cli.producer.Close()
+	var failed int32
+	go func() {
+		for e := range cli.producer.Events() {
+			if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+				log.Printf("message delivery failed: %v\n", m.TopicPartition.Error)
+				atomic.AddInt32(&failed, 1)
+			}
+		}
+	}()
+	config := cli.getKafkaConfig(cli.ConsumerSettings)
+	config.SetKey("group.id", cli.GroupID)
+	config.SetKey("auto.offset.reset", "earliest")
+	config.SetKey("enable.auto.commit", false)
+	config.SetKey("enable.partition.eof", true)
+	config.SetKey("go.application.rebalance.enable", true)
+	if cli.consumer, err = kafka.NewConsumer(config); err != nil {
+		return fmt.Errorf("could not create consumer: %v", err)
+	}
+	defer cli.consumer.Close()
+	if err := cli.consumer.Subscribe(cli.DeadLetterTopic, nil); err != nil {
+		return fmt.Errorf("cannot subscribe to %s: %v", cli.DeadLetterTopic, err)
+	}
+
+	// Replay until reaching the end of all the assigned partitions
+	replayed, skipped := 0, 0
+	pending := make(map[int32]bool)
+	assigned := false
+	deadline := time.Now().Add(*timeout)
+	for !assigned || len(pending) > 0 {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for messages from %s", cli.DeadLetterTopic)
+		}
+		switch e := cli.consumer.Poll(1000).(type) {
+		case kafka.AssignedPartitions:
+			cli.consumer.Assign(e.Partitions)
+			for _, p := range e.Partitions {
+				pending[p.Partition] = true
+			}
+			assigned = true
+			deadline = time.Now().Add(*timeout)
+		case kafka.RevokedPartitions:
+			// Commit what was replayed so far, as another consumer will continue from there
+			if err := commitReplay(cli, &failed); err != nil {
+				return err
+			}
+			cli.consumer.Unassign()
+			for _, p := range e.Partitions {
+				delete(pending, p.Partition)
+			}
+		case kafka.PartitionEOF:
+			delete(pending, e.Partition)
+		case *kafka.Message:
+			deadline = time.Now().Add(*timeout)
+			m := replayMessage(e, *destTopic, *kind)
+			if m == nil {
+				skipped++
+				continue
+			}
+			if err := produceReplay(cli, m); err != nil {
+				return fmt.Errorf("cannot replay %s: %v", e.TopicPartition, err)
+			}
+			replayed++
+		case kafka.Error:
+			log.Printf("kafka consumer error: %v\n", e)
+		}
+	}
+	if err := commitReplay(cli, &failed); err != nil {
+		return err
+	}
+	log.Printf("%d messages replayed, %d skipped\n", replayed, skipped)
+	return nil
+}
+
+// replayMessage builds the message to re-drive a given dead-letter message without the dead-letter headers; returns nil if it must be skipped.
+func replayMessage(msg *kafka.Message, destTopic, kind string) *kafka.Message {
+	headers := make([]kafka.Header, 0, len(msg.Headers))
+	var sourceTopic, sourceKind string
+	for _, h := range msg.Headers {
+		switch h.Key {
+		case dlqTopicHeader:
+			sourceTopic = string(h.Value)
+		case dlqKindHeader:
+			sourceKind = string(h.Value)
+		case dlqErrorHeader, dlqPartitionHeader, dlqOffsetHeader:
+		default:
+			headers = append(headers, h)
+		}
+	}
+	if kind != "" && kind != sourceKind {
+		return nil
+	}
+	topic := destTopic
+	if topic == "" {
+		topic = sourceTopic
+	}
+	if topic == "" {
+		log.Printf("cannot determine the destination topic for %s, skipping\n", msg.TopicPartition)
+		return nil
+	}
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            msg.Key,
+		Value:          msg.Value,
+		Headers:        headers,
+	}
+}
+
+// produceReplay sends a replayed message, waiting for the pending deliveries while the producer queue is full.
+func produceReplay(cli *KafkaClient, m *kafka.Message) error {
+	for {
+		err := cli.producer.Produce(m, nil)
+		if kerr, ok := err.(kafka.Error); ok && kerr.Code() == kafka.ErrQueueFull {
+			cli.producer.Flush(1000)
+			continue
+		}
+		return err
+	}
+}
+
+// commitReplay waits for the replayed messages to be delivered, and commits the offsets of the dead-letter topic if all of them succeeded.
+func commitReplay(cli *KafkaClient, failed *int32) error {
+	for cli.producer.Flush(1000) > 0 {
+		log.Println("waiting for pending deliveries")
+	}
+	if failed := atomic.LoadInt32(failed); failed > 0 {
+		return fmt.Errorf("%d messages could not be replayed; offsets not committed", failed)
+	}
+	if _, err := cli.consumer.Commit(); err != nil {
+		if kerr, ok := err.(kafka.Error); !ok || kerr.Code() != kafka.ErrNoOffset {
+			return fmt.Errorf("cannot commit offsets: %v", err)
+		}
+	}
+	return nil
+}
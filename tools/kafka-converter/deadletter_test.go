@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"gotest.tools/assert"
+)
+
+func TestReplayMessage(t *testing.T) {
+	dlq := "converter-dlq"
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &dlq, Partition: 0, Offset: 5},
+		Key:            []byte("key"),
+		Value:          []byte{0x08, 0x01},
+		Headers: []kafka.Header{
+			{Key: "source", Value: []byte("onms")},
+			{Key: dlqErrorHeader, Value: []byte("cannot parse GPB")},
+			{Key: dlqTopicHeader, Value: []byte("OpenNMS-alarms")},
+			{Key: dlqPartitionHeader, Value: []byte("2")},
+			{Key: dlqOffsetHeader, Value: []byte("10")},
+			{Key: dlqKindHeader, Value: []byte(alarmKind)},
+		},
+	}
+
+	// The dead-letter headers are stripped, and the source topic is the destination
+	m := replayMessage(msg, "", "")
+	assert.Assert(t, m != nil)
+	assert.Equal(t, "OpenNMS-alarms", *m.TopicPartition.Topic)
+	assert.Equal(t, kafka.PartitionAny, m.TopicPartition.Partition)
+	assert.DeepEqual(t, msg.Key, m.Key)
+	assert.DeepEqual(t, msg.Value, m.Value)
+	assert.DeepEqual(t, []kafka.Header{{Key: "source", Value: []byte("onms")}}, m.Headers)
+
+	// Explicit destination topic and kind filter
+	m = replayMessage(msg, "alarms-replay", alarmKind)
+	assert.Assert(t, m != nil)
+	assert.Equal(t, "alarms-replay", *m.TopicPartition.Topic)
+	assert.Assert(t, replayMessage(msg, "", eventKind) == nil)
+	assert.Assert(t, replayMessage(msg, "", recordKind) == nil)
+
+	// Without the source topic header, the message is skipped unless there is a destination topic
+	msg.Headers = msg.Headers[:1]
+	assert.Assert(t, replayMessage(msg, "", "") == nil)
+	assert.Assert(t, replayMessage(msg, "", alarmKind) == nil)
+	m = replayMessage(msg, "alarms-replay", "")
+	assert.Assert(t, m != nil)
+	assert.Equal(t, "alarms-replay", *m.TopicPartition.Topic)
+}
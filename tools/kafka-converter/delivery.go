@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Valid delivery guarantees.
+const (
+	autoCommitGuarantee  = "auto"          // offsets are committed periodically regardless of the delivery reports
+	atLeastOnceGuarantee = "at-least-once" // offsets are committed after all the records derived from a message are delivered
+	exactlyOnceGuarantee = "exactly-once"  // offsets and records are committed together using Kafka transactions
+)
+
+var deliveryGuarantees = []string{autoCommitGuarantee, atLeastOnceGuarantee, exactlyOnceGuarantee}
+
+// Maximum time to wait between retries of a failed delivery.
+const maxRetryBackoff = 30 * time.Second
+
+// Maximum time to keep retrying the commit of a transaction before aborting it.
+const transactionCommitTimeout = time.Minute
+
+// delivery tracks the records produced from a given source message.
+type delivery struct {
+	partition kafka.TopicPartition
+	pending   int
+	sealed    bool
+	done      bool
+	sources   []*delivery // Released once completed, when the records are derived from several source messages
+}
+
+// outbound is the opaque of each produced record when deliveries are tracked.
+type outbound struct {
+	delivery *delivery
+	attempts int
+}
+
+type partitionKey struct {
+	topic     string
+	partition int32
+}
+
+// OffsetTracker stores the offset of the source messages once all the records derived from them are delivered.
+// Offsets are stored in order per partition, so a message is never considered processed before the previous ones.
+type OffsetTracker struct {
+	Store    func(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error)
+	mutex    sync.Mutex
+	inflight map[partitionKey][]*delivery
+	closed   bool
+}
+
+// NewOffsetTracker creates a new tracker that uses the given function to store offsets.
+func NewOffsetTracker(store func(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error)) *OffsetTracker {
+	return &OffsetTracker{
+		Store:    store,
+		inflight: make(map[partitionKey][]*delivery),
+	}
+}
+
+// Begin starts tracking a source message.
+func (t *OffsetTracker) Begin(msg *kafka.Message) *delivery {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	d := &delivery{partition: msg.TopicPartition}
+	key := partitionKey{*msg.TopicPartition.Topic, msg.TopicPartition.Partition}
+	queue := t.inflight[key]
+	if n := len(queue); n > 0 && queue[n-1].partition.Offset >= msg.TopicPartition.Offset {
+		// The partition was rewound, probably due to a rebalance
+		queue = nil
+	}
+	t.inflight[key] = append(queue, d)
+	return d
+}
+
+// Add registers a new record derived from a source message; returns false if the message cannot be tracked anymore.
+// A sealed message still accepts records while others are pending, as its offset has not been stored yet.
+func (t *OffsetTracker) Add(d *delivery) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if d.done {
+		return false
+	}
+	d.pending++
+	return true
+}
+
+// Seal indicates that no more records will be derived from a source message.
+func (t *OffsetTracker) Seal(d *delivery) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	d.sealed = true
+	if d.pending == 0 {
+		t.complete(d)
+	}
+}
+
+// Done indicates that a record derived from a source message has been delivered.
+func (t *OffsetTracker) Done(d *delivery) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	d.pending--
+	if d.sealed && d.pending == 0 {
+		t.complete(d)
+	}
+}
+
+// complete marks a message as processed and stores the offset of the last contiguous processed message; the mutex must be held.
+func (t *OffsetTracker) complete(d *delivery) {
+	d.done = true
+	if d.sources != nil {
+		for _, src := range d.sources {
+			if src.pending--; src.sealed && src.pending == 0 {
+				t.complete(src)
+			}
+		}
+		return
+	}
+	key := partitionKey{*d.partition.Topic, d.partition.Partition}
+	queue := t.inflight[key]
+	var last *delivery
+	for len(queue) > 0 && queue[0].done {
+		last = queue[0]
+		queue = queue[1:]
+	}
+	t.inflight[key] = queue
+	if last == nil || t.closed {
+		return
+	}
+	tp := last.partition
+	tp.Offset++ // The committed offset is the next message to read
+	if _, err := t.Store([]kafka.TopicPartition{tp}); err != nil {
+		log.Printf("cannot store offset for %s: %v\n", tp, err)
+	}
+}
+
+// Group starts tracking the records derived from several source messages, which must be held by the caller.
+// The source messages are released once all the records are delivered and Done is called for the group.
+func (t *OffsetTracker) Group(sources []*delivery) *delivery {
+	return &delivery{partition: sources[0].partition, pending: 1, sealed: true, sources: sources}
+}
+
+// Close stops storing offsets, as the consumer might be closed while late delivery reports are still being processed.
+func (t *OffsetTracker) Close() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.closed = true
+}
+
+// holdDelivery keeps a source message in flight, when its delivery is tracked, until releaseDelivery is called.
+func (cli *KafkaClient) holdDelivery(msg *kafka.Message) {
+	if d, ok := msg.Opaque.(*delivery); ok && !cli.tracker.Add(d) {
+		msg.Opaque = nil // Already completed, so there is nothing to release
+	}
+}
+
+// releaseDelivery releases a source message held by holdDelivery.
+func (cli *KafkaClient) releaseDelivery(msg *kafka.Message) {
+	if d, ok := msg.Opaque.(*delivery); ok {
+		cli.tracker.Done(d)
+	}
+}
+
+// groupDeliveries returns a message to track the records derived from several messages held by holdDelivery.
+// It must be released by releaseDelivery after producing the records, and the held messages are released once all of them are delivered.
+func (cli *KafkaClient) groupDeliveries(sources []*kafka.Message) *kafka.Message {
+	deliveries := make([]*delivery, 0, len(sources))
+	for _, src := range sources {
+		if d, ok := src.Opaque.(*delivery); ok {
+			deliveries = append(deliveries, d)
+		}
+	}
+	if len(deliveries) == 0 {
+		return &kafka.Message{}
+	}
+	return &kafka.Message{Opaque: cli.tracker.Group(deliveries)}
+}
+
+// send produces a message, retrying later in case of errors.
+func (cli *KafkaClient) send(m *kafka.Message) {
+	if cli.sink != nil {
+		if err := cli.sink.Write(m); err != nil {
+			log.Printf("cannot write message for %s: %v\n", *m.TopicPartition.Topic, err)
+		}
+		return
+	}
+	if err := cli.producer.Produce(m, nil); err != nil {
+		log.Printf("cannot produce message to %s: %v\n", *m.TopicPartition.Topic, err)
+		cli.retry(m)
+	}
+}
+
+// retry produces a message again after a backoff when deliveries are tracked.
+func (cli *KafkaClient) retry(m *kafka.Message) {
+	out, ok := m.Opaque.(*outbound)
+	if !ok {
+		return
+	}
+	if out.attempts >= cli.DeliveryRetries {
+		err := fmt.Errorf("cannot deliver message to %s after %d attempts", *m.TopicPartition.Topic, out.attempts+1)
+		if cli.DeadLetterTopic == "" || *m.TopicPartition.Topic == cli.DeadLetterTopic {
+			// The delivery is never released, so the offset of the source message is not committed
+			cli.fail(err)
+			return
+		}
+		cli.deadLetterRecord(m, out.delivery, err)
+		return
+	}
+	cli.retryMutex.Lock()
+	defer cli.retryMutex.Unlock()
+	if cli.retriesStopped {
+		// The delivery is never released, so the source message is consumed again after a restart
+		log.Printf("discarding message to %s while stopping\n", *m.TopicPartition.Topic)
+		return
+	}
+	out.attempts++
+	backoff := 100 * time.Millisecond << uint(out.attempts)
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: m.TopicPartition.Topic, Partition: kafka.PartitionAny},
+		Key:            m.Key,
+		Value:          m.Value,
+		Headers:        m.Headers,
+		Opaque:         out,
+	}
+	cli.pendingRetries++
+	time.AfterFunc(backoff, func() {
+		cli.send(msg)
+		cli.retryMutex.Lock()
+		cli.pendingRetries--
+		cli.retryMutex.Unlock()
+	})
+}
+
+// flushProducer stops scheduling retries and waits until there are no records in flight and no pending retries.
+func (cli *KafkaClient) flushProducer() {
+	cli.retryMutex.Lock()
+	cli.retriesStopped = true
+	cli.retryMutex.Unlock()
+	for {
+		inflight := cli.producer.Flush(1000)
+		cli.retryMutex.Lock()
+		retries := cli.pendingRetries
+		cli.retryMutex.Unlock()
+		if inflight == 0 && retries == 0 {
+			return
+		}
+		log.Printf("waiting for %d records in flight and %d pending retries\n", inflight, retries)
+	}
+}
+
+// deadLetterRecord sends a record that could not be delivered to the dead-letter topic, keeping its source message in flight until then.
+// The source topic header is the original destination of the record, so replaying it completes the delivery.
+func (cli *KafkaClient) deadLetterRecord(m *kafka.Message, d *delivery, err error) {
+	topic := cli.DeadLetterTopic
+	headers := append(append([]kafka.Header{}, m.Headers...),
+		kafka.Header{Key: dlqErrorHeader, Value: []byte(err.Error())},
+		kafka.Header{Key: dlqTopicHeader, Value: []byte(*m.TopicPartition.Topic)},
+		kafka.Header{Key: dlqPartitionHeader, Value: []byte(strconv.Itoa(int(d.partition.Partition)))},
+		kafka.Header{Key: dlqOffsetHeader, Value: []byte(strconv.FormatInt(int64(d.partition.Offset), 10))},
+		kafka.Header{Key: dlqKindHeader, Value: []byte(recordKind)},
+	)
+	log.Printf("%v, sending it to %s\n", err, topic)
+	cli.send(&kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            m.Key,
+		Value:          m.Value,
+		Headers:        headers,
+		Opaque:         &outbound{delivery: d},
+	})
+}
+
+// handleDeliveryReport processes the delivery report of a produced message.
+func (cli *KafkaClient) handleDeliveryReport(m *kafka.Message) {
+	if m.TopicPartition.Error != nil {
+		log.Printf("message delivery failed: %v\n", m.TopicPartition.Error)
+		cli.retry(m)
+		return
+	}
+	log.Printf("message delivered to %v\n", m.TopicPartition)
+	if out, ok := m.Opaque.(*outbound); ok {
+		cli.tracker.Done(out.delivery)
+	}
+}
+
+// transactionalLoop consumes messages in batches, committing the produced records and the consumed offsets within a Kafka transaction.
+func (cli *KafkaClient) transactionalLoop() {
+	ctx := context.Background()
+	for {
+		select {
+		case <-cli.stopping:
+			return
+		default:
+		}
+		msg := cli.pollMessage(1000)
+		if msg == nil {
+			continue
+		}
+		if err := cli.producer.BeginTransaction(); err != nil {
+			cli.fail(fmt.Errorf("cannot begin transaction: %v", err))
+			return
+		}
+		// Keep the first offset per partition to rewind the consumer if the transaction is aborted
+		first := make(map[partitionKey]kafka.TopicPartition)
+		process := func(m *kafka.Message) {
+			key := partitionKey{*m.TopicPartition.Topic, m.TopicPartition.Partition}
+			if _, ok := first[key]; !ok {
+				first[key] = m.TopicPartition
+			}
+			cli.processMessage(m)
+		}
+		process(msg)
+		deadline := time.Now().Add(cli.TransactionTime)
+		for count := 1; count < cli.TransactionBatch && time.Now().Before(deadline); {
+			if msg := cli.pollMessage(100); msg != nil {
+				process(msg)
+				count++
+			}
+		}
+		if err := cli.commitTransaction(ctx); err != nil {
+			if kerr, ok := err.(kafka.Error); ok && kerr.IsFatal() {
+				cli.fail(fmt.Errorf("fatal error committing transaction: %v", err))
+				return
+			}
+			log.Printf("transaction failed, rewinding: %v\n", err)
+			if err := cli.producer.AbortTransaction(ctx); err != nil {
+				cli.fail(fmt.Errorf("cannot abort transaction: %v", err))
+				return
+			}
+			for _, tp := range first {
+				if err := cli.consumer.Seek(tp, 10000); err != nil {
+					log.Printf("cannot rewind %s: %v\n", tp, err)
+				}
+			}
+		}
+	}
+}
+
+// pollMessage returns the next message from the consumer, or nil if there is none within the timeout.
+func (cli *KafkaClient) pollMessage(timeoutMs int) *kafka.Message {
+	switch e := cli.consumer.Poll(timeoutMs).(type) {
+	case *kafka.Message:
+		return e
+	case kafka.Error:
+		log.Printf("kafka consumer error: %v\n", e)
+	}
+	return nil
+}
+
+// commitTransaction sends the consumed offsets to the current transaction and commits it, retrying when possible.
+func (cli *KafkaClient) commitTransaction(ctx context.Context) error {
+	assignment, err := cli.consumer.Assignment()
+	if err != nil {
+		return err
+	}
+	positions, err := cli.consumer.Position(assignment)
+	if err != nil {
+		return err
+	}
+	metadata, err := cli.consumer.GetConsumerGroupMetadata()
+	if err != nil {
+		return err
+	}
+	if err := cli.producer.SendOffsetsToTransaction(ctx, positions, metadata); err != nil {
+		return fmt.Errorf("cannot send offsets to transaction: %v", err)
+	}
+	// Retriable errors are retried with backoff, up to a deadline
+	ctx, cancel := context.WithTimeout(ctx, transactionCommitTimeout)
+	defer cancel()
+	backoff := 100 * time.Millisecond
+	for {
+		err := cli.producer.CommitTransaction(ctx)
+		if err == nil {
+			return nil
+		}
+		if kerr, ok := err.(kafka.Error); ok && kerr.IsRetriable() && !kerr.IsFatal() {
+			log.Printf("retrying transaction commit in %s: %v\n", backoff, err)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return fmt.Errorf("cannot commit transaction within %s: %v", transactionCommitTimeout, err)
+			}
+			if backoff *= 2; backoff > maxRetryBackoff {
+				backoff = maxRetryBackoff
+			}
+			continue
+		}
+		return err
+	}
+}
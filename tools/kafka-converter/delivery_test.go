@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"gotest.tools/assert"
+)
+
+func TestOffsetTracker(t *testing.T) {
+	stored := make([]kafka.Offset, 0)
+	tracker := NewOffsetTracker(func(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+		for _, tp := range offsets {
+			stored = append(stored, tp.Offset)
+		}
+		return offsets, nil
+	})
+	topic := "OpenNMS-alarms"
+	msg := func(offset int64) *kafka.Message {
+		return &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: kafka.Offset(offset)}}
+	}
+
+	// Message 10 produces 2 records, message 11 produces 1 record, and message 12 produces nothing
+	d10 := tracker.Begin(msg(10))
+	assert.Assert(t, tracker.Add(d10))
+	assert.Assert(t, tracker.Add(d10))
+	tracker.Seal(d10)
+	d11 := tracker.Begin(msg(11))
+	assert.Assert(t, tracker.Add(d11))
+	tracker.Seal(d11)
+	d12 := tracker.Begin(msg(12))
+	tracker.Seal(d12)
+	assert.Equal(t, 0, len(stored))
+	assert.Assert(t, !tracker.Add(d12))
+
+	// Message 11 is delivered first, but the offset cannot be stored until message 10 is fully delivered
+	tracker.Done(d11)
+	assert.Equal(t, 0, len(stored))
+	tracker.Done(d10)
+	assert.Equal(t, 0, len(stored))
+	tracker.Done(d10)
+	assert.DeepEqual(t, []kafka.Offset{13}, stored)
+
+	// Late deliveries don't store offsets once the tracker is closed
+	d13 := tracker.Begin(msg(13))
+	assert.Assert(t, tracker.Add(d13))
+	tracker.Seal(d13)
+	tracker.Close()
+	tracker.Done(d13)
+	assert.DeepEqual(t, []kafka.Offset{13}, stored)
+}
+
+func TestGroupDeliveries(t *testing.T) {
+	stored := make([]kafka.TopicPartition, 0)
+	cli := &KafkaClient{tracker: NewOffsetTracker(func(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+		stored = append(stored, offsets...)
+		return offsets, nil
+	})}
+	topic := "OpenNMS-metrics"
+	sources := make([]*kafka.Message, 0)
+	for partition := int32(0); partition < 2; partition++ {
+		msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: partition, Offset: 10}}
+		msg.Opaque = cli.tracker.Begin(msg)
+		cli.holdDelivery(msg)
+		cli.tracker.Seal(msg.Opaque.(*delivery))
+		sources = append(sources, msg)
+	}
+
+	// The held messages of both partitions are released once the record derived from them is delivered
+	src := cli.groupDeliveries(sources)
+	group := src.Opaque.(*delivery)
+	assert.Assert(t, cli.tracker.Add(group))
+	cli.releaseDelivery(src)
+	assert.Equal(t, 0, len(stored))
+	cli.tracker.Done(group)
+	assert.Equal(t, 2, len(stored))
+	assert.Equal(t, kafka.Offset(11), stored[0].Offset)
+	assert.Equal(t, kafka.Offset(11), stored[1].Offset)
+}
+
+func TestRetryGiveUp(t *testing.T) {
+	stored := 0
+	cli := &KafkaClient{
+		DeliveryRetries: 1,
+		tracker: NewOffsetTracker(func(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+			stored++
+			return offsets, nil
+		}),
+		failed: make(chan error, 1),
+	}
+	source, dest := "OpenNMS-alarms", "alarms"
+	d := cli.tracker.Begin(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &source, Offset: 10}})
+	assert.Assert(t, cli.tracker.Add(d))
+	cli.tracker.Seal(d)
+
+	// Without a dead-letter topic, the client fails and the offset is never stored
+	cli.retry(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &dest}, Opaque: &outbound{delivery: d, attempts: 1}})
+	select {
+	case err := <-cli.failed:
+		assert.ErrorContains(t, err, "cannot deliver message to alarms after 2 attempts")
+	default:
+		t.Fatal("the client did not fail")
+	}
+	assert.Equal(t, 0, stored)
+	assert.Assert(t, !d.done)
+}
+
+func TestRetryWhileStopping(t *testing.T) {
+	cli := &KafkaClient{DeliveryRetries: 10, retriesStopped: true}
+	dest := "alarms"
+	out := &outbound{attempts: 1}
+	cli.retry(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &dest}, Opaque: out})
+	assert.Equal(t, 1, out.attempts)
+	assert.Equal(t, 0, cli.pendingRetries)
+}
+
+func TestHoldDelivery(t *testing.T) {
+	stored := 0
+	cli := &KafkaClient{tracker: NewOffsetTracker(func(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+		stored++
+		return offsets, nil
+	})}
+	topic := "OpenNMS-alarms"
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: 10}}
+	msg.Opaque = cli.tracker.Begin(msg)
+	cli.holdDelivery(msg)
+	cli.tracker.Seal(msg.Opaque.(*delivery))
+	assert.Equal(t, 0, stored)
+	cli.releaseDelivery(msg)
+	assert.Equal(t, 1, stored)
+
+	// Untracked messages are ignored
+	untracked := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: 11}}
+	cli.holdDelivery(untracked)
+	cli.releaseDelivery(untracked)
+	assert.Equal(t, 1, stored)
+}
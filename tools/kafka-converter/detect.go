@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/agalue/kafka-converter/api/flows"
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// The kind to use when the message kind has to be detected for each message.
+const autoKind = "auto"
+
+// Minimum score required to accept a given kind, either inferred from the topic name or detected by decoding the payload.
+const minDetectionScore = 3
+
+// Milliseconds since epoch for 2001-09-09; used to verify that timestamps make sense.
+const minPlausibleTime = 1000000000000
+
+// Topic name conventions used by the OpenNMS Kafka Producer; the order matters.
+var topicHints = []struct {
+	pattern string
+	kind    string
+}{
+	{"feedback", feedbackKind},
+	{"alarm", alarmKind},
+	{"event", eventKind},
+	{"node", nodeKind},
+	{"edge", edgeKind},
+	{"topology", edgeKind},
+	{"metric", metricKind},
+	{"collection", metricKind},
+	{"flow", flowKind},
+}
+
+// kindFromTopic returns the message kind based on the topic name, or an empty string if it cannot be inferred.
+func kindFromTopic(topic string) string {
+	topic = strings.ToLower(topic)
+	for _, hint := range topicHints {
+		if strings.Contains(topic, hint.pattern) {
+			return hint.kind
+		}
+	}
+	return ""
+}
+
+// detectKind figures out the message kind of a given payload.
+// It tries the kind inferred from the topic name first, and if that doesn't work,
+// it decodes the payload against each kind and chooses the one with the best plausibility score.
+func detectKind(topic string, payload []byte) (string, proto.Message, error) {
+	if kind := kindFromTopic(topic); kind != "" {
+		if data, score := scoreKind(kind, payload); score >= minDetectionScore {
+			return kind, data, nil
+		}
+	}
+	var bestKind string
+	var bestData proto.Message
+	bestScore, secondScore := -1, -1
+	for _, kind := range kinds {
+		data, score := scoreKind(kind, payload)
+		if score > bestScore {
+			secondScore = bestScore
+			bestKind, bestData, bestScore = kind, data, score
+		} else if score > secondScore {
+			secondScore = score
+		}
+	}
+	if bestScore < minDetectionScore {
+		return "", nil, fmt.Errorf("cannot classify message; best candidate %s has a score of %d", bestKind, bestScore)
+	}
+	if bestScore == secondScore {
+		return "", nil, fmt.Errorf("cannot classify message; more than one kind has a score of %d", bestScore)
+	}
+	return bestKind, bestData, nil
+}
+
+// scoreKind decodes the payload as the given kind and returns the decoded message and its plausibility score.
+// A negative score means the payload cannot be a message of the given kind.
+func scoreKind(kind string, payload []byte) (proto.Message, int) {
+	data := newMessage(kind)
+	if err := proto.Unmarshal(payload, data); err != nil {
+		return nil, -1
+	}
+	if hasUnknownFields(proto.MessageReflect(data)) {
+		return nil, -1
+	}
+	switch msg := data.(type) {
+	case *producer.Event:
+		return data, scoreEvent(msg)
+	case *producer.Alarm:
+		return data, scoreAlarm(msg)
+	case *producer.Node:
+		return data, scoreNode(msg)
+	case *producer.TopologyEdge:
+		return data, scoreEdge(msg)
+	case *producer.CollectionSet:
+		return data, scoreCollectionSet(msg)
+	case *producer.AlarmFeedback:
+		return data, scoreFeedback(msg)
+	case *flows.FlowDocument:
+		return data, scoreFlow(msg)
+	}
+	return nil, -1
+}
+
+func hasUnknownFields(msg protoreflect.Message) bool {
+	if len(msg.GetUnknown()) > 0 {
+		return true
+	}
+	unknown := false
+	msg.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind {
+			return true
+		}
+		switch {
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len() && !unknown; i++ {
+				unknown = hasUnknownFields(list.Get(i).Message())
+			}
+		case fd.IsMap():
+			// There are no maps on the OpenNMS Producer messages
+		default:
+			unknown = hasUnknownFields(v.Message())
+		}
+		return !unknown
+	})
+	return unknown
+}
+
+func isValidEnum(v protoreflect.Enum) bool {
+	return v.Descriptor().Values().ByNumber(v.Number()) != nil
+}
+
+func isPlausibleTime(t uint64) bool {
+	return t >= minPlausibleTime
+}
+
+func isPlausibleUEI(uei string) bool {
+	return strings.HasPrefix(uei, "uei.")
+}
+
+func scoreEvent(e *producer.Event) int {
+	score := 0
+	if !isValidEnum(e.Severity) {
+		return -1
+	}
+	if isPlausibleUEI(e.Uei) {
+		score += 2
+	}
+	if e.Id > 0 {
+		score++
+	}
+	if isPlausibleTime(e.Time) {
+		score++
+	}
+	if e.Source != "" {
+		score++
+	}
+	return score
+}
+
+func scoreAlarm(a *producer.Alarm) int {
+	score := 0
+	if !isValidEnum(a.Severity) || !isValidEnum(a.Type) {
+		return -1
+	}
+	if isPlausibleUEI(a.Uei) {
+		score += 2
+	}
+	if a.ReductionKey != "" {
+		score += 2
+	}
+	if a.Id > 0 {
+		score++
+	}
+	if a.Count > 0 {
+		score++
+	}
+	if isPlausibleTime(a.FirstEventTime) || isPlausibleTime(a.LastEventTime) {
+		score++
+	}
+	return score
+}
+
+func scoreNode(n *producer.Node) int {
+	score := 0
+	if n.Label != "" {
+		score += 2
+	}
+	if n.Id > 0 {
+		score++
+	}
+	if n.ForeignSource != "" && n.ForeignId != "" {
+		score++
+	}
+	if n.Location != "" {
+		score++
+	}
+	if isPlausibleTime(n.CreateTime) {
+		score++
+	}
+	return score
+}
+
+func scoreEdge(e *producer.TopologyEdge) int {
+	score := 0
+	if e.Ref == nil {
+		return score
+	}
+	if !isValidEnum(e.Ref.Protocol) {
+		return -1
+	}
+	if e.Ref.Id != "" {
+		score += 2
+	}
+	if e.Source != nil {
+		score++
+	}
+	if e.Target != nil {
+		score++
+	}
+	return score
+}
+
+func scoreCollectionSet(c *producer.CollectionSet) int {
+	score := 0
+	if c.Timestamp >= minPlausibleTime {
+		score += 2
+	}
+	if len(c.Resource) == 0 {
+		return score
+	}
+	score++
+	for _, r := range c.Resource {
+		if r.Resource == nil {
+			return score
+		}
+		for _, n := range r.Numeric {
+			if n.Name == "" || !isValidEnum(n.Type) {
+				return -1
+			}
+		}
+	}
+	return score + 1
+}
+
+func scoreFeedback(f *producer.AlarmFeedback) int {
+	score := 0
+	if !isValidEnum(f.FeedbackType) {
+		return -1
+	}
+	if f.SituationKey != "" {
+		score += 2
+	}
+	if f.SituationFingerprint != "" {
+		score++
+	}
+	if f.AlarmKey != "" {
+		score++
+	}
+	if isPlausibleTime(f.Timestamp) {
+		score++
+	}
+	return score
+}
+
+func scoreFlow(f *flows.FlowDocument) int {
+	score := 0
+	if !isValidEnum(f.Direction) || !isValidEnum(f.NetflowVersion) {
+		return -1
+	}
+	if net.ParseIP(f.SrcAddress) != nil {
+		score++
+	}
+	if net.ParseIP(f.DstAddress) != nil {
+		score++
+	}
+	if f.Protocol != nil {
+		score++
+	}
+	if isPlausibleTime(f.Timestamp) {
+		score++
+	}
+	return score
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/agalue/kafka-converter/api/flows"
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"gotest.tools/assert"
+)
+
+func TestDetectKind(t *testing.T) {
+	alarm := &producer.Alarm{
+		Id:             1,
+		Uei:            "uei.opennms.org/nodes/nodeDown",
+		ReductionKey:   "uei.opennms.org/nodes/nodeDown::1",
+		Count:          1,
+		Severity:       producer.Severity_MAJOR,
+		FirstEventTime: 1600000000000,
+	}
+	node := &producer.Node{
+		Id:            1,
+		ForeignSource: "Test",
+		ForeignId:     "001",
+		Label:         "srv01",
+		Location:      "Default",
+	}
+	metric := &producer.CollectionSet{
+		Timestamp: 1600000000000,
+		Resource: []*producer.CollectionSetResource{
+			{
+				Resource: &producer.CollectionSetResource_Node{Node: &producer.NodeLevelResource{NodeId: 1}},
+				Numeric:  []*producer.NumericAttribute{{Group: "mib2-tcp", Name: "tcpActiveOpens", Value: 10}},
+			},
+		},
+	}
+	feedback := &producer.AlarmFeedback{
+		SituationKey:         "uei.opennms.org/alarms/situation::1",
+		SituationFingerprint: "NDg3ZjdiMjJmNjgzMTJkMmMxYmJjOTNiMWFlYTQ0NWI=",
+		AlarmKey:             "uei.opennms.org/nodes/nodeDown::1",
+		FeedbackType:         producer.AlarmFeedback_FALSE_POSITIVE,
+		Timestamp:            1600000000000,
+	}
+	flow := &flows.FlowDocument{
+		Timestamp:  1600000000000,
+		SrcAddress: "10.0.0.1",
+		DstAddress: "10.0.0.2",
+		Protocol:   wrapperspb.UInt32(6),
+		NumBytes:   wrapperspb.UInt64(1000),
+	}
+	tests := []struct {
+		topic string
+		msg   proto.Message
+		kind  string
+	}{
+		{"OpenNMS-alarms", alarm, alarmKind},
+		{"unknown", alarm, alarmKind},
+		{"unknown", node, nodeKind},
+		{"OpenNMS-alarms", node, nodeKind},
+		{"unknown", metric, metricKind},
+		{"OpenNMS-alarm-feedback", feedback, feedbackKind},
+		{"unknown", feedback, feedbackKind},
+		{"opennms-flows", flow, flowKind},
+		{"unknown", flow, flowKind},
+	}
+	for _, test := range tests {
+		payload, err := proto.Marshal(test.msg)
+		assert.NilError(t, err)
+		kind, data, err := detectKind(test.topic, payload)
+		assert.NilError(t, err)
+		assert.Equal(t, test.kind, kind)
+		assert.Assert(t, proto.Equal(test.msg, data))
+	}
+}
+
+func TestDetectKindUnclassified(t *testing.T) {
+	payload, err := proto.Marshal(&producer.Node{Id: 1})
+	assert.NilError(t, err)
+	_, _, err = detectKind("unknown", payload)
+	assert.ErrorContains(t, err, "cannot classify message")
+
+	// The topic name doesn't lower the score required to accept its kind
+	_, _, err = detectKind("OpenNMS-nodes", payload)
+	assert.ErrorContains(t, err, "cannot classify message")
+}
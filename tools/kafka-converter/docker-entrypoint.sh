@@ -39,6 +39,49 @@ exec /kafka-converter \
   -dest-topic-flat "${DEST_TOPIC_FLAT}" \
   -group-id "${GROUP_ID-opennms}" \
   -message-kind "${MESSAGE_KIND-alarm}" \
+  -routes "${ROUTES}" \
+  -filter "${FILTER}" \
+  -transform "${TRANSFORM}" \
+  -descriptor-files "${DESCRIPTOR_FILES}" \
+  -json-format "${JSON_FORMAT-legacy}" \
+  -json-enum-strings "${JSON_ENUM_STRINGS-true}" \
+  -json-timestamps "${JSON_TIMESTAMPS-false}" \
+  -cloudevents "${CLOUDEVENTS}" \
+  -tombstones "${TOMBSTONES-tombstone}" \
+  -lifecycle-topic "${LIFECYCLE_TOPIC}" \
+  -dead-letter-topic "${DEAD_LETTER_TOPIC}" \
+  -delivery-guarantee "${DELIVERY_GUARANTEE-auto}" \
+  -transactional-id "${TRANSACTIONAL_ID}" \
+  -enrich-nodes-topic "${ENRICH_NODES_TOPIC}" \
+  -enrich-dest-topic "${ENRICH_DEST_TOPIC}" \
+  -enrich-missing-node "${ENRICH_MISSING_NODE-emit}" \
+  -http-listen "${HTTP_LISTEN}" \
+  -alarm-table-topic "${ALARM_TABLE_TOPIC}" \
+  -topology-topic "${TOPOLOGY_TOPIC}" \
+  -prometheus-ttl "${PROMETHEUS_TTL-5m}" \
+  -remote-write-url "${REMOTE_WRITE_URL}" \
+  -influx-topic "${INFLUX_TOPIC}" \
+  -influx-url "${INFLUX_URL}" \
+  -influx-token "${INFLUX_TOKEN}" \
+  -influx-string-attributes "${INFLUX_STRING_ATTRIBUTES-tag}" \
+  -explode-topic "${EXPLODE_TOPIC}" \
+  -explode-mode "${EXPLODE_MODE-resource}" \
+  -counter-rates "${COUNTER_RATES-false}" \
+  -counter-state-topic "${COUNTER_STATE_TOPIC}" \
+  -counter-snapshot-file "${COUNTER_SNAPSHOT_FILE}" \
+  -rollups "${ROLLUPS}" \
+  -rollup-allowed-lateness "${ROLLUP_ALLOWED_LATENESS-1m}" \
+  -feedback-report-topic "${FEEDBACK_REPORT_TOPIC}" \
+  -feedback-report-window "${FEEDBACK_REPORT_WINDOW-1h}" \
+  -input-files "${INPUT_FILES}" \
+  -input-format "${INPUT_FORMAT-capture}" \
+  -output-dir "${OUTPUT_DIR}" \
+  -output-rotate-size "${OUTPUT_ROTATE_SIZE-100}" \
+  -output-rotate-interval "${OUTPUT_ROTATE_INTERVAL-1h}" \
+  -parquet-dir "${PARQUET_DIR}" \
+  -parquet-kinds "${PARQUET_KINDS-alarm,event,node,metric}" \
+  -parquet-rotate-size "${PARQUET_ROTATE_SIZE-128}" \
+  -parquet-rotate-interval "${PARQUET_ROTATE_INTERVAL-15m}" \
   -producer-params "$(join , ${PRODUCER[@]})" \
   -consumer-params "$(join , ${CONSUMER[@]})" \
   -debug ${DEBUG}
\ No newline at end of file
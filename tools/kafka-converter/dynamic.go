@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// The key used to render the unknown fields of the dynamic messages.
+const unknownFieldsKey = "_unknown"
+
+// Message types loaded at runtime from descriptor files, indexed by full name.
+// Each of them can be used as a message kind (i.e. org.opennms.netmgt.flows.persistence.model.FlowDocument).
+var dynamicTypes = make(map[string]protoreflect.MessageDescriptor)
+
+func isDynamicKind(kind string) bool {
+	_, ok := dynamicTypes[kind]
+	return ok
+}
+
+// newDynamicMessage returns an empty dynamic message for a given kind, or nil if it doesn't exist.
+func newDynamicMessage(kind string) proto.Message {
+	if md, ok := dynamicTypes[kind]; ok {
+		return dynamicpb.NewMessage(md)
+	}
+	return nil
+}
+
+// descriptorResolver resolves the dependencies of the loaded files, falling back to the compiled-in ones (i.e. google/protobuf/wrappers.proto).
+type descriptorResolver struct {
+	files *protoregistry.Files
+}
+
+func (r descriptorResolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := r.files.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r descriptorResolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := r.files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
+
+// loadDescriptors loads the message types from a CSV of files, adding them to the dynamic types.
+// Each file is either a FileDescriptorSet (i.e. protoc --include_imports --descriptor_set_out) or a .proto file, compiled with protoc.
+func loadDescriptors(paths string) error {
+	resolver := descriptorResolver{files: new(protoregistry.Files)}
+	for _, path := range strings.Split(paths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		data, err := readDescriptorSet(path)
+		if err != nil {
+			return err
+		}
+		set := &descriptorpb.FileDescriptorSet{}
+		if err := proto.Unmarshal(data, set); err != nil {
+			return fmt.Errorf("invalid descriptor set %s: %v", path, err)
+		}
+		for _, fdp := range set.File {
+			if _, err := resolver.files.FindFileByPath(fdp.GetName()); err == nil {
+				continue // Already loaded from another set
+			}
+			fd, err := protodesc.NewFile(fdp, resolver)
+			if err != nil {
+				return fmt.Errorf("invalid descriptor %s on %s: %v", fdp.GetName(), path, err)
+			}
+			if err := resolver.files.RegisterFile(fd); err != nil {
+				return fmt.Errorf("cannot register descriptor %s from %s: %v", fdp.GetName(), path, err)
+			}
+			addDynamicTypes(fd.Messages())
+		}
+	}
+	log.Printf("%d message types loaded from %s\n", len(dynamicTypes), paths)
+	return nil
+}
+
+func addDynamicTypes(messages protoreflect.MessageDescriptors) {
+	for i := 0; i < messages.Len(); i++ {
+		md := messages.Get(i)
+		if md.IsMapEntry() {
+			continue
+		}
+		dynamicTypes[string(md.FullName())] = md
+		addDynamicTypes(md.Messages())
+	}
+}
+
+func readDescriptorSet(path string) ([]byte, error) {
+	if !strings.HasSuffix(path, ".proto") {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read descriptor set %s: %v", path, err)
+		}
+		return data, nil
+	}
+	protoc, err := exec.LookPath("protoc")
+	if err != nil {
+		return nil, fmt.Errorf("protoc is required to compile %s; use a descriptor set instead", path)
+	}
+	out, err := ioutil.TempFile("", "descriptor-*.pb")
+	if err != nil {
+		return nil, err
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+	cmd := exec.Command(protoc, "--include_imports", "--descriptor_set_out="+out.Name(), "-I", filepath.Dir(path), filepath.Base(path))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("cannot compile %s: %v %s", path, err, strings.TrimSpace(string(output)))
+	}
+	return ioutil.ReadFile(out.Name())
+}
+
+// marshalDynamic returns the JSON representation of a dynamic message, including its unknown fields.
+// The legacy format is not possible without generated structs, so the proto field names are used instead.
+func (e *JSONEncoder) marshalDynamic(msg *dynamicpb.Message) ([]byte, error) {
+	protoNames := e.Format != camelJSONFormat
+	opts := protojson.MarshalOptions{
+		UseProtoNames:  protoNames,
+		UseEnumNumbers: !e.EnumsAsStrings,
+	}
+	jsonBytes, err := opts.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	// protojson output is deliberately unstable; make it compact to have a predictable result
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, jsonBytes); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := writeUnknownFields(&buf, compact.Bytes(), msg, protoNames); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeUnknownFields copies the JSON representation of a given message, adding the unknown fields of the message and its nested messages
+// at the end of their objects, and preserving the order of the other keys.
+// Unknown fields are indexed by field number, and as the type cannot be known, the raw wire values are used:
+// numbers for varint and fixed fields, and strings for length-delimited fields (base64 unless they are valid UTF-8).
+func writeUnknownFields(buf *bytes.Buffer, raw json.RawMessage, msg protoreflect.Message, protoNames bool) error {
+	fields := msg.Descriptor().Fields()
+	rewrite := func(key string, value json.RawMessage) ([]byte, error) {
+		fd := fields.ByJSONName(key)
+		if protoNames {
+			fd = fields.ByName(protoreflect.Name(key))
+		}
+		if fd == nil || (fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind) || fd.IsMap() {
+			// The keys of the maps are rendered as strings, which makes the lookup ambiguous; unknown fields are ignored.
+			return value, nil
+		}
+		var nested bytes.Buffer
+		if !fd.IsList() {
+			if value[0] != '{' {
+				return value, nil // Well-known types rendered as scalars
+			}
+			err := writeUnknownFields(&nested, value, msg.Get(fd).Message(), protoNames)
+			return nested.Bytes(), err
+		}
+		var items []json.RawMessage
+		if err := json.Unmarshal(value, &items); err != nil {
+			return nil, err
+		}
+		list := msg.Get(fd).List()
+		nested.WriteByte('[')
+		for i, item := range items {
+			if i > 0 {
+				nested.WriteByte(',')
+			}
+			if item[0] != '{' || i >= list.Len() {
+				nested.Write(item)
+			} else if err := writeUnknownFields(&nested, item, list.Get(i).Message(), protoNames); err != nil {
+				return nil, err
+			}
+		}
+		nested.WriteByte(']')
+		return nested.Bytes(), nil
+	}
+	extra := make([]jsonField, 0)
+	if unknown := decodeUnknownFields(msg.GetUnknown()); len(unknown) > 0 {
+		extra = append(extra, jsonField{unknownFieldsKey, unknown})
+	}
+	return rewriteObject(buf, raw, rewrite, extra...)
+}
+
+func decodeUnknownFields(raw protoreflect.RawFields) map[string][]interface{} {
+	fields := make(map[string][]interface{})
+	for len(raw) > 0 {
+		num, typ, n := protowire.ConsumeTag(raw)
+		if n < 0 {
+			break
+		}
+		raw = raw[n:]
+		var value interface{}
+		switch typ {
+		case protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(raw)
+			value = v
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(raw)
+			value = v
+		case protowire.Fixed64Type:
+			var v uint64
+			v, n = protowire.ConsumeFixed64(raw)
+			value = v
+		case protowire.BytesType:
+			var v []byte
+			v, n = protowire.ConsumeBytes(raw)
+			if utf8.Valid(v) {
+				value = string(v)
+			} else {
+				value = base64.StdEncoding.EncodeToString(v)
+			}
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, raw)
+		}
+		if n < 0 {
+			break
+		}
+		raw = raw[n:]
+		if value != nil {
+			key := strconv.Itoa(int(num))
+			fields[key] = append(fields[key], value)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"gotest.tools/assert"
+)
+
+func writeDescriptorSet(t *testing.T) string {
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	field := func(name string, number int32, label descriptorpb.FieldDescriptorProto_Label, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Label: &label, Type: &typ}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("custom.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/wrappers.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Custom"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("timestamp", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_UINT64, ""),
+					field("child", 3, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Child"),
+					field("children", 4, repeated, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Child"),
+					field("count", 5, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.UInt32Value"),
+				},
+			},
+			{
+				Name:  proto.String("Child"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("value", 1, optional, descriptorpb.FieldDescriptorProto_TYPE_INT32, "")},
+			},
+		},
+	}
+	set := &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(wrapperspb.File_google_protobuf_wrappers_proto), file},
+	}
+	data, err := proto.Marshal(set)
+	assert.NilError(t, err)
+	dir, err := ioutil.TempDir("", "descriptors")
+	assert.NilError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "custom.pb")
+	assert.NilError(t, ioutil.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestDynamicMessage(t *testing.T) {
+	// Remove the loaded types, as they are global
+	t.Cleanup(func() {
+		for kind := range dynamicTypes {
+			delete(dynamicTypes, kind)
+		}
+	})
+	assert.NilError(t, loadDescriptors(writeDescriptorSet(t)))
+	assert.Assert(t, isValidKind("test.Custom"))
+	assert.Assert(t, isValidKind("test.Child"))
+	assert.Assert(t, !isValidKind("test.Missing"))
+
+	route := Route{SourceTopic: "custom", MessageKind: "test.Custom", DestTopics: []string{"custom-json"}, Filter: `name == "srv01"`}
+	assert.NilError(t, route.validate())
+
+	// Build a message with unknown fields at the root and on a nested message
+	child := protowire.AppendTag(nil, 1, protowire.VarintType)
+	child = protowire.AppendVarint(child, 7)
+	child = protowire.AppendTag(child, 9, protowire.Fixed32Type)
+	child = protowire.AppendFixed32(child, 42)
+	var payload []byte
+	payload = protowire.AppendTag(payload, 1, protowire.BytesType)
+	payload = protowire.AppendString(payload, "srv01")
+	payload = protowire.AppendTag(payload, 2, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, 1600000000000)
+	payload = protowire.AppendTag(payload, 4, protowire.BytesType)
+	payload = protowire.AppendBytes(payload, child)
+	payload = protowire.AppendTag(payload, 5, protowire.BytesType)
+	payload = protowire.AppendBytes(payload, protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 3))
+	payload = protowire.AppendTag(payload, 10, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, 5)
+	payload = protowire.AppendTag(payload, 11, protowire.BytesType)
+	payload = protowire.AppendString(payload, "new field")
+
+	data := newMessage("test.Custom")
+	assert.NilError(t, proto.Unmarshal(payload, data))
+	_, ok := data.(*dynamicpb.Message)
+	assert.Assert(t, ok)
+	assert.Assert(t, route.accepts("custom-json", "test.Custom", data))
+
+	tests := []struct {
+		encoder  *JSONEncoder
+		expected string
+	}{
+		{
+			&JSONEncoder{Format: legacyJSONFormat},
+			`{"name":"srv01","timestamp":"1600000000000","children":[{"value":7,"_unknown":{"9":[42]}}],"count":3,"_unknown":{"10":[5],"11":["new field"]}}`,
+		},
+		{
+			&JSONEncoder{Format: camelJSONFormat, TimesAsRFC3339: true},
+			`{"name":"srv01","timestamp":"2020-09-13T12:26:40.000Z","children":[{"value":7,"_unknown":{"9":[42]}}],"count":3,"_unknown":{"10":[5],"11":["new field"]}}`,
+		},
+	}
+	// The unknown fields are appended, preserving the order of the known fields
+	for _, test := range tests {
+		jsonBytes, err := test.encoder.Marshal(data)
+		assert.NilError(t, err)
+		assert.Equal(t, test.expected, string(jsonBytes))
+	}
+}
+
+func TestLoadDescriptorsInvalid(t *testing.T) {
+	assert.ErrorContains(t, loadDescriptors("/missing/file.pb"), "cannot read descriptor set")
+}
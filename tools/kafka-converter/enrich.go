@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/proto"
+)
+
+// Valid policies for alarms associated with unknown nodes.
+const (
+	missingNodeEmit  = "emit"  // emit the alarm without the node
+	missingNodeDrop  = "drop"  // discard the alarm
+	missingNodeDefer = "defer" // wait for the node, and emit the alarm without it after a timeout
+)
+
+var missingNodePolicies = []string{missingNodeEmit, missingNodeDrop, missingNodeDefer}
+
+// The kind of the enhanced alarms, for the CloudEvents type.
+const enhancedAlarmKind = alarmKind + ".enhanced"
+
+func nodeIDKey(id uint64) string {
+	return "id:" + strconv.FormatUint(id, 10)
+}
+
+func nodeFSKey(foreignSource, foreignID string) string {
+	return "fs:" + foreignSource + ":" + foreignID
+}
+
+// NodeTable represents an in-memory table of nodes, indexed by ID and by foreign source and foreign ID.
+type NodeTable struct {
+	mutex sync.RWMutex
+	byKey map[string]*producer.Node // Indexed by the Kafka record key
+	nodes map[string]*producer.Node // Indexed by nodeIDKey and nodeFSKey
+}
+
+// NewNodeTable creates an empty node table.
+func NewNodeTable() *NodeTable {
+	return &NodeTable{
+		byKey: make(map[string]*producer.Node),
+		nodes: make(map[string]*producer.Node),
+	}
+}
+
+// Update adds or replaces the node associated with a given record key; a nil node removes it.
+func (t *NodeTable) Update(key string, node *producer.Node) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if old, ok := t.byKey[key]; ok {
+		t.unindex(old)
+		delete(t.byKey, key)
+	} else if node == nil {
+		// The record key from OpenNMS is either foreign-source:foreign-id or the node ID
+		if id, err := strconv.ParseUint(key, 10, 64); err == nil {
+			delete(t.nodes, nodeIDKey(id))
+		} else if array := strings.SplitN(key, ":", 2); len(array) == 2 {
+			delete(t.nodes, nodeFSKey(array[0], array[1]))
+		}
+	}
+	if node == nil {
+		return
+	}
+	t.byKey[key] = node
+	if node.Id > 0 {
+		t.nodes[nodeIDKey(node.Id)] = node
+	}
+	if node.ForeignSource != "" && node.ForeignId != "" {
+		t.nodes[nodeFSKey(node.ForeignSource, node.ForeignId)] = node
+	}
+}
+
+func (t *NodeTable) unindex(node *producer.Node) {
+	if t.nodes[nodeIDKey(node.Id)] == node {
+		delete(t.nodes, nodeIDKey(node.Id))
+	}
+	if t.nodes[nodeFSKey(node.ForeignSource, node.ForeignId)] == node {
+		delete(t.nodes, nodeFSKey(node.ForeignSource, node.ForeignId))
+	}
+}
+
+// Lookup returns the node matching the given criteria, or nil if it is unknown.
+func (t *NodeTable) Lookup(criteria *producer.NodeCriteria) *producer.Node {
+	if criteria == nil {
+		return nil
+	}
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	if node, ok := t.nodes[nodeIDKey(criteria.Id)]; ok && criteria.Id > 0 {
+		return node
+	}
+	if criteria.ForeignSource != "" && criteria.ForeignId != "" {
+		return t.nodes[nodeFSKey(criteria.ForeignSource, criteria.ForeignId)]
+	}
+	return nil
+}
+
+// Size returns the number of nodes in the table.
+func (t *NodeTable) Size() int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return len(t.byKey)
+}
+
+// EnhancedAlarm represents an alarm with the node associated with it, as expected by the serverless functions.
+type EnhancedAlarm struct {
+	Alarm json.RawMessage `json:"alarm"`
+	Node  json.RawMessage `json:"node,omitempty"`
+}
+
+// pendingAlarm represents an alarm waiting for its node.
+type pendingAlarm struct {
+	msg      *kafka.Message
+	alarm    *producer.Alarm
+	deadline time.Time
+}
+
+// Enricher joins alarms with the nodes from the nodes topic.
+type Enricher struct {
+	NodesTopic   string
+	MissingNode  string
+	DeferTimeout time.Duration
+	Emit         func(msg *kafka.Message, alarm *producer.Alarm, node *producer.Node)
+	Hold         func(msg *kafka.Message) // Optional, called when an alarm is deferred
+	Release      func(msg *kafka.Message) // Optional, called after a deferred alarm is emitted
+	table        *NodeTable
+	reader       *TopicReader
+	mutex        sync.Mutex
+	pending      map[string][]*pendingAlarm
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// Start builds the node table from the beginning of the nodes topic, and keeps it updated.
+func (e *Enricher) Start(config *kafka.ConfigMap) error {
+	e.table = NewNodeTable()
+	e.pending = make(map[string][]*pendingAlarm)
+	e.reader = &TopicReader{Topic: e.NodesTopic, Handler: e.updateNode}
+	if err := e.reader.Start(config); err != nil {
+		return err
+	}
+	log.Printf("node table initialized with %d nodes\n", e.table.Size())
+	if e.MissingNode == missingNodeDefer {
+		e.stop = make(chan struct{})
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case now := <-ticker.C:
+					e.expire(now)
+				case <-e.stop:
+					return
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// Stop stops reading the nodes topic, and emits the pending alarms without nodes.
+func (e *Enricher) Stop() {
+	if e.stop != nil {
+		close(e.stop)
+		e.wg.Wait()
+		e.expire(time.Now().Add(e.DeferTimeout))
+	}
+	if e.reader != nil {
+		e.reader.Stop()
+	}
+}
+
+func (e *Enricher) updateNode(msg *kafka.Message) {
+	key := string(msg.Key)
+	if msg.Value == nil {
+		e.table.Update(key, nil)
+		return
+	}
+	node := &producer.Node{}
+	if err := proto.Unmarshal(msg.Value, node); err != nil {
+		log.Printf("invalid node message received from %s: %v\n", msg.TopicPartition, err)
+		return
+	}
+	e.table.Update(key, node)
+	if e.MissingNode == missingNodeDefer {
+		for _, p := range e.takePending(node) {
+			e.emit(p, node)
+		}
+	}
+}
+
+// Process joins a given alarm with its node, applying the missing node policy when the node is unknown.
+func (e *Enricher) Process(msg *kafka.Message, alarm *producer.Alarm) {
+	c := alarm.NodeCriteria
+	if c == nil || (c.Id == 0 && c.ForeignSource == "") {
+		e.Emit(msg, alarm, nil)
+		return
+	}
+	// The lookup and the deferral must be atomic, to not miss a node that arrives in between
+	e.mutex.Lock()
+	node := e.table.Lookup(c)
+	if node == nil && e.MissingNode == missingNodeDefer {
+		key := nodeIDKey(c.Id)
+		if c.ForeignSource != "" && c.ForeignId != "" {
+			key = nodeFSKey(c.ForeignSource, c.ForeignId)
+		}
+		if e.Hold != nil {
+			e.Hold(msg)
+		}
+		e.pending[key] = append(e.pending[key], &pendingAlarm{msg, alarm, time.Now().Add(e.DeferTimeout)})
+		e.mutex.Unlock()
+		return
+	}
+	e.mutex.Unlock()
+	if node != nil {
+		e.Emit(msg, alarm, node)
+		return
+	}
+	if e.MissingNode == missingNodeDrop {
+		log.Printf("node %s for alarm %d is unknown, dropping alarm\n", criteriaSubject(c), alarm.Id)
+		return
+	}
+	e.Emit(msg, alarm, nil)
+}
+
+// emit sends a deferred alarm, and releases its source message.
+func (e *Enricher) emit(p *pendingAlarm, node *producer.Node) {
+	e.Emit(p.msg, p.alarm, node)
+	if e.Release != nil {
+		e.Release(p.msg)
+	}
+}
+
+// takePending removes and returns the alarms waiting for a given node.
+func (e *Enricher) takePending(node *producer.Node) []*pendingAlarm {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	result := make([]*pendingAlarm, 0)
+	for _, key := range []string{nodeIDKey(node.Id), nodeFSKey(node.ForeignSource, node.ForeignId)} {
+		result = append(result, e.pending[key]...)
+		delete(e.pending, key)
+	}
+	return result
+}
+
+// expire emits the deferred alarms without node once their deadline has passed.
+func (e *Enricher) expire(now time.Time) {
+	expired := make([]*pendingAlarm, 0)
+	e.mutex.Lock()
+	for key, list := range e.pending {
+		remaining := list[:0]
+		for _, p := range list {
+			if now.Before(p.deadline) {
+				remaining = append(remaining, p)
+			} else {
+				expired = append(expired, p)
+			}
+		}
+		if len(remaining) == 0 {
+			delete(e.pending, key)
+		} else {
+			e.pending[key] = remaining
+		}
+	}
+	e.mutex.Unlock()
+	for _, p := range expired {
+		log.Printf("node %s for alarm %d is still unknown, emitting alarm without node\n", criteriaSubject(p.alarm.NodeCriteria), p.alarm.Id)
+		e.emit(p, nil)
+	}
+}
+
+func validateMissingNodePolicy(policy string) error {
+	if contains(missingNodePolicies, policy) {
+		return nil
+	}
+	return fmt.Errorf("invalid missing node policy %s. Valid options: %s", policy, strings.Join(missingNodePolicies, ", "))
+}
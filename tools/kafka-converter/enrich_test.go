@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/proto"
+	"gotest.tools/assert"
+)
+
+func TestNodeTable(t *testing.T) {
+	table := NewNodeTable()
+	node := &producer.Node{Id: 1, ForeignSource: "Test", ForeignId: "001", Label: "srv01"}
+	table.Update("Test:001", node)
+	assert.Equal(t, node, table.Lookup(&producer.NodeCriteria{Id: 1}))
+	assert.Equal(t, node, table.Lookup(&producer.NodeCriteria{ForeignSource: "Test", ForeignId: "001"}))
+	assert.Assert(t, table.Lookup(&producer.NodeCriteria{Id: 2}) == nil)
+
+	table.Update("Test:001", nil)
+	assert.Equal(t, 0, table.Size())
+	assert.Assert(t, table.Lookup(&producer.NodeCriteria{Id: 1}) == nil)
+}
+
+func TestEnricherDefer(t *testing.T) {
+	emitted := make(map[uint64]*producer.Node)
+	e := &Enricher{
+		MissingNode:  missingNodeDefer,
+		DeferTimeout: time.Minute,
+		Emit: func(msg *kafka.Message, alarm *producer.Alarm, node *producer.Node) {
+			emitted[alarm.Id] = node
+		},
+		table:   NewNodeTable(),
+		pending: make(map[string][]*pendingAlarm),
+	}
+	held := 0
+	e.Hold = func(msg *kafka.Message) { held++ }
+	e.Release = func(msg *kafka.Message) { held-- }
+	e.Process(&kafka.Message{}, &producer.Alarm{Id: 1, NodeCriteria: &producer.NodeCriteria{Id: 1}})
+	e.Process(&kafka.Message{}, &producer.Alarm{Id: 2, NodeCriteria: &producer.NodeCriteria{Id: 2}})
+	assert.Equal(t, 0, len(emitted))
+	assert.Equal(t, 2, held)
+
+	node, err := proto.Marshal(&producer.Node{Id: 1, Label: "srv01"})
+	assert.NilError(t, err)
+	e.updateNode(&kafka.Message{Key: []byte("1"), Value: node})
+	assert.Equal(t, 1, len(emitted))
+	assert.Equal(t, "srv01", emitted[1].Label)
+	assert.Equal(t, 1, held)
+
+	e.expire(time.Now().Add(2 * time.Minute))
+	assert.Equal(t, 2, len(emitted))
+	assert.Assert(t, emitted[2] == nil)
+	assert.Equal(t, 0, held)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Valid modes to explode a CollectionSet.
+const (
+	explodeByResource = "resource" // one record per CollectionSetResource
+	explodeBySample   = "sample"   // one record per NumericAttribute
+)
+
+var explodeModes = []string{explodeByResource, explodeBySample}
+
+// NumericSample represents a numeric attribute of a resource.
+type NumericSample struct {
+	Group string  `json:"group"`
+	Name  string  `json:"name"`
+	Type  string  `json:"type"`
+	Value float64 `json:"value"`
+}
+
+// ResourceRecord represents a CollectionSetResource with its resolved identity.
+type ResourceRecord struct {
+	ResourceID string `json:"resource_id"`
+	Timestamp  int64  `json:"timestamp"`
+	ResourceInfo
+	Strings map[string]string `json:"strings,omitempty"`
+	Numeric []NumericSample   `json:"numeric,omitempty"`
+}
+
+// SampleRecord represents a single numeric attribute with the resolved identity of its resource.
+type SampleRecord struct {
+	ResourceID string `json:"resource_id"`
+	Timestamp  int64  `json:"timestamp"`
+	ResourceInfo
+	NumericSample
+}
+
+// ExplodedRecord represents a record derived from a CollectionSet, keyed by the resource ID.
+type ExplodedRecord struct {
+	Key   string
+	Value interface{}
+}
+
+// explodeCollectionSet splits a CollectionSet into one record per resource or per numeric sample.
+func explodeCollectionSet(cs *producer.CollectionSet, mode string) []ExplodedRecord {
+	records := make([]ExplodedRecord, 0)
+	for _, resource := range cs.Resource {
+		info := resolveResource(resource)
+		id := info.ID()
+		samples := make([]NumericSample, 0, len(resource.Numeric))
+		for _, attr := range resource.Numeric {
+			samples = append(samples, NumericSample{
+				Group: attr.Group,
+				Name:  attr.Name,
+				Type:  attr.Type.String(),
+				Value: attr.Value,
+			})
+		}
+		if mode == explodeBySample {
+			for _, sample := range samples {
+				records = append(records, ExplodedRecord{id, &SampleRecord{id, cs.Timestamp, info, sample}})
+			}
+			continue
+		}
+		record := &ResourceRecord{
+			ResourceID:   id,
+			Timestamp:    cs.Timestamp,
+			ResourceInfo: info,
+			Numeric:      samples,
+		}
+		if len(resource.String_) > 0 {
+			record.Strings = make(map[string]string, len(resource.String_))
+			for _, attr := range resource.String_ {
+				record.Strings[attr.Name] = attr.Value
+			}
+		}
+		records = append(records, ExplodedRecord{id, record})
+	}
+	return records
+}
+
+func (cli *KafkaClient) processExplode(msg *kafka.Message, cs *producer.CollectionSet) {
+	for _, record := range explodeCollectionSet(cs, cli.ExplodeMode) {
+		value, err := json.Marshal(record.Value)
+		if err == nil && cli.JSONTimestamps {
+			value, err = convertTimestamps(value)
+		}
+		if err != nil {
+			log.Printf("cannot convert record for resource %s: %v\n", record.Key, err)
+			continue
+		}
+		cli.produce(msg, cli.ExplodeTopic, []byte(record.Key), value)
+		if cli.Debug {
+			log.Printf("exploded record: %s\n", string(value))
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"gotest.tools/assert"
+)
+
+func TestExplodeCollectionSet(t *testing.T) {
+	node := &producer.NodeLevelResource{NodeId: 1, ForeignSource: "Production", ForeignId: "srv01", NodeLabel: "srv01"}
+	cs := &producer.CollectionSet{
+		Timestamp: 1600000000000,
+		Resource: []*producer.CollectionSetResource{
+			{
+				Resource: &producer.CollectionSetResource_Interface{Interface: &producer.InterfaceLevelResource{Node: node, Instance: "eth0"}},
+				String_:  []*producer.StringAttribute{{Name: "ifName", Value: "eth0"}},
+				Numeric: []*producer.NumericAttribute{
+					{Group: "mib2-interfaces", Name: "ifHCInOctets", Value: 1234, Type: producer.NumericAttribute_COUNTER},
+					{Group: "mib2-interfaces", Name: "ifHCOutOctets", Value: 5678, Type: producer.NumericAttribute_COUNTER},
+				},
+			},
+			{
+				Resource: &producer.CollectionSetResource_Response{Response: &producer.ResponseTimeResource{Instance: "10.0.0.1", Location: "Default"}},
+				Numeric:  []*producer.NumericAttribute{{Group: "icmp", Name: "icmp", Value: 1.5}},
+			},
+		},
+	}
+
+	records := explodeCollectionSet(cs, explodeByResource)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, "Production:srv01/interface/eth0", records[0].Key)
+	assert.Equal(t, "responseTime/Default/10.0.0.1", records[1].Key)
+	data, err := json.Marshal(records[1].Value)
+	assert.NilError(t, err)
+	assert.Equal(t, `{"resource_id":"responseTime/Default/10.0.0.1","timestamp":1600000000000,"location":"Default","resource_type":"responseTime","instance":"10.0.0.1","numeric":[{"group":"icmp","name":"icmp","type":"GAUGE","value":1.5}]}`, string(data))
+
+	records = explodeCollectionSet(cs, explodeBySample)
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, "Production:srv01/interface/eth0", records[1].Key)
+	data, err = json.Marshal(records[1].Value)
+	assert.NilError(t, err)
+	assert.Equal(t, `{"resource_id":"Production:srv01/interface/eth0","timestamp":1600000000000,"node_id":1,"foreign_source":"Production","foreign_id":"srv01","node_label":"srv01","resource_type":"interface","instance":"eth0","group":"mib2-interfaces","name":"ifHCOutOctets","type":"COUNTER","value":5678}`, string(data))
+}
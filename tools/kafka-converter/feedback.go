@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// FeedbackSummary represents the number of feedback messages of each type received for a situation within a window.
+type FeedbackSummary struct {
+	Fingerprint   string `json:"situation_fingerprint"`
+	SituationKey  string `json:"situation_key,omitempty"`
+	Window        string `json:"window"`
+	Start         int64  `json:"window_start"`
+	End           int64  `json:"window_end"`
+	FalsePositive int    `json:"false_positive"`
+	FalseNegative int    `json:"false_negative"`
+	Correct       int    `json:"correct"`
+	Unknown       int    `json:"unknown"`
+	Total         int    `json:"total"`
+}
+
+func (s *FeedbackSummary) add(t producer.AlarmFeedback_FeedbackType) {
+	switch t {
+	case producer.AlarmFeedback_FALSE_POSITIVE:
+		s.FalsePositive++
+	case producer.AlarmFeedback_FALSE_NEGATIVE:
+		s.FalseNegative++
+	case producer.AlarmFeedback_CORRECT:
+		s.Correct++
+	default:
+		s.Unknown++
+	}
+	s.Total++
+}
+
+// FeedbackReport aggregates the alarm feedback messages into tumbling windows per situation fingerprint.
+type FeedbackReport struct {
+	*TumblingWindow
+	Topic string
+}
+
+// NewFeedbackReport creates a new report for a given window size.
+func NewFeedbackReport(window time.Duration, topic string, lateness time.Duration) *FeedbackReport {
+	return &FeedbackReport{
+		TumblingWindow: NewTumblingWindow(window, lateness),
+		Topic:          topic,
+	}
+}
+
+// Add aggregates a given feedback, and returns the windows closed as a result and their source messages.
+// The timestamp (in milliseconds) is used when the feedback doesn't have one.
+func (r *FeedbackReport) Add(msg *kafka.Message, feedback *producer.AlarmFeedback, timestamp int64) ([]*FeedbackSummary, []*kafka.Message) {
+	if feedback.Timestamp > 0 {
+		timestamp = int64(feedback.Timestamp)
+	}
+	win := r.open(msg, timestamp)
+	if win == nil {
+		log.Printf("late feedback for situation %s dropped by the %s report\n", feedback.SituationFingerprint, r.Window)
+		return nil, nil
+	}
+	fingerprint := feedback.SituationFingerprint
+	if fingerprint == "" {
+		fingerprint = feedback.SituationKey
+	}
+	summary := win.state(fingerprint, func() interface{} {
+		return &FeedbackSummary{
+			Fingerprint:  fingerprint,
+			SituationKey: feedback.SituationKey,
+			Window:       r.Window.String(),
+			Start:        win.start,
+			End:          win.end,
+		}
+	}).(*FeedbackSummary)
+	summary.add(feedback.FeedbackType)
+	states, sources := r.advance(timestamp)
+	summaries := make([]*FeedbackSummary, len(states))
+	for i, state := range states {
+		summaries[i] = state.(*FeedbackSummary)
+	}
+	return summaries, sources
+}
+
+func (cli *KafkaClient) processFeedback(msg *kafka.Message, feedback *producer.AlarmFeedback) {
+	cli.emitFeedback(cli.feedback.Add(msg, feedback, msg.Timestamp.UnixNano()/1e6))
+}
+
+// emitFeedback sends the summaries of the closed windows, releasing their source messages once all of them are delivered.
+func (cli *KafkaClient) emitFeedback(summaries []*FeedbackSummary, sources []*kafka.Message) {
+	if len(sources) == 0 {
+		return
+	}
+	src := cli.groupDeliveries(sources)
+	defer cli.releaseDelivery(src)
+	for _, summary := range summaries {
+		value, err := json.Marshal(summary)
+		if err == nil && cli.JSONTimestamps {
+			value, err = convertTimestamps(value)
+		}
+		if err != nil {
+			log.Printf("cannot convert feedback report for %s: %v\n", summary.Fingerprint, err)
+			continue
+		}
+		cli.produce(src, cli.feedback.Topic, []byte(summary.Fingerprint), value)
+		if cli.Debug {
+			log.Printf("feedback report: %s\n", string(value))
+		}
+	}
+}
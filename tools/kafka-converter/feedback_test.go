@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"gotest.tools/assert"
+)
+
+func buildFeedback(fingerprint string, t producer.AlarmFeedback_FeedbackType, ts uint64) *producer.AlarmFeedback {
+	return &producer.AlarmFeedback{
+		SituationKey:         "situation::" + fingerprint,
+		SituationFingerprint: fingerprint,
+		AlarmKey:             "uei.opennms.org/nodes/nodeDown::1",
+		FeedbackType:         t,
+		Timestamp:            ts,
+	}
+}
+
+func TestFeedbackReport(t *testing.T) {
+	r := NewFeedbackReport(time.Hour, "feedback-report", time.Minute)
+	held := 0
+	r.Hold = func(msg *kafka.Message) { held++ }
+	add := func(feedback *producer.AlarmFeedback, ts int64) ([]*FeedbackSummary, []*kafka.Message) {
+		return r.Add(&kafka.Message{}, feedback, ts)
+	}
+	base := uint64(1600002000000) // aligned to 1 hour
+	closed, _ := add(buildFeedback("b", producer.AlarmFeedback_CORRECT, base+1000), 0)
+	assert.Equal(t, 0, len(closed))
+	closed, _ = add(buildFeedback("a", producer.AlarmFeedback_FALSE_POSITIVE, base+2000), 0)
+	assert.Equal(t, 0, len(closed))
+	closed, _ = add(buildFeedback("a", producer.AlarmFeedback_FALSE_POSITIVE, base+3000), 0)
+	assert.Equal(t, 0, len(closed))
+	closed, _ = add(buildFeedback("a", producer.AlarmFeedback_FALSE_NEGATIVE, base+4000), 0)
+	assert.Equal(t, 0, len(closed))
+
+	// Without timestamp, the given one is used
+	closed, _ = add(buildFeedback("a", producer.AlarmFeedback_CORRECT, 0), int64(base+5000))
+	assert.Equal(t, 0, len(closed))
+
+	// Within the allowed lateness
+	closed, _ = add(buildFeedback("a", producer.AlarmFeedback_UNKNOWN, base+3600000+30000), 0)
+	assert.Equal(t, 0, len(closed))
+
+	closed, sources := add(buildFeedback("b", producer.AlarmFeedback_CORRECT, base+3600000+60000), 0)
+	assert.Equal(t, 2, len(closed))
+	assert.Equal(t, 5, len(sources))
+	a, b := closed[0], closed[1]
+	assert.Equal(t, "a", a.Fingerprint)
+	assert.Equal(t, "situation::a", a.SituationKey)
+	assert.Equal(t, "1h0m0s", a.Window)
+	assert.Equal(t, int64(base), a.Start)
+	assert.Equal(t, int64(base+3600000), a.End)
+	assert.Equal(t, 2, a.FalsePositive)
+	assert.Equal(t, 1, a.FalseNegative)
+	assert.Equal(t, 1, a.Correct)
+	assert.Equal(t, 0, a.Unknown)
+	assert.Equal(t, 4, a.Total)
+	assert.Equal(t, "b", b.Fingerprint)
+	assert.Equal(t, 1, b.Correct)
+	assert.Equal(t, 1, b.Total)
+
+	// Late feedback is dropped without holding its message
+	closed, sources = add(buildFeedback("a", producer.AlarmFeedback_CORRECT, base+10000), 0)
+	assert.Equal(t, 0, len(closed))
+	assert.Equal(t, 0, len(sources))
+	assert.Equal(t, 7, held)
+}
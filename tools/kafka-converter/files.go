@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Valid formats for the input files.
+const (
+	captureInput   = "capture"   // JSON records created by the capture command, with their own topics
+	delimitedInput = "delimited" // GPB messages prefixed by their size as a varint (i.e. writeDelimitedTo in Java)
+	singleInput    = "single"    // One GPB message per file
+)
+
+var inputFormats = []string{captureInput, delimitedInput, singleInput}
+
+// Maximum size of a GPB message within a delimited file, to detect files in the wrong format.
+const maxDelimitedSize = 64 * 1024 * 1024
+
+// expandInputFiles returns the files from a CSV of files, directories or glob patterns, sorted by name within each entry.
+func expandInputFiles(paths string) ([]string, error) {
+	files := make([]string, 0)
+	for _, entry := range strings.Split(paths, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		matches, err := filepath.Glob(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input pattern %s: %v", entry, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no input files found for %s", entry)
+		}
+		sort.Strings(matches)
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				files = append(files, path)
+				continue
+			}
+			list, err := ioutil.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+			for _, f := range list {
+				if !f.IsDir() {
+					files = append(files, filepath.Join(path, f.Name()))
+				}
+			}
+		}
+	}
+	return files, nil
+}
+
+// readInputFile reads the messages of a given file, calling a function for each of them.
+// Except for captures, the messages are associated with a given topic, and use the modification time of the file as timestamp.
+func readInputFile(path string, format string, topic string, handler func(msg *kafka.Message)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %v", path, err)
+	}
+	defer f.Close()
+	if format == captureInput {
+		if err := readCapture(f, handler); err != nil {
+			return fmt.Errorf("cannot read %s: %v", path, err)
+		}
+		return nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	offset := kafka.Offset(0)
+	newMessage := func(value []byte) *kafka.Message {
+		msg := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: offset},
+			Value:          value,
+			Timestamp:      info.ModTime(),
+		}
+		offset++
+		return msg
+	}
+	if format == singleInput {
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			return fmt.Errorf("cannot read %s: %v", path, err)
+		}
+		handler(newMessage(data))
+		return nil
+	}
+	reader := bufio.NewReader(f)
+	for {
+		size, err := binary.ReadUvarint(reader)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read message size at %d on %s: %v", offset, path, err)
+		}
+		if size > maxDelimitedSize {
+			return fmt.Errorf("invalid message size %d at %d on %s", size, offset, path)
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return fmt.Errorf("cannot read message at %d on %s: %v", offset, path, err)
+		}
+		handler(newMessage(data))
+	}
+}
+
+// fileLoop processes the messages from the input files, and signals when all of them were processed.
+func (cli *KafkaClient) fileLoop() {
+	defer close(cli.done)
+	topic := ""
+	for t := range cli.routes {
+		topic = t // There is only one route when the format is not a capture
+	}
+	count := 0
+	for _, path := range cli.inputFiles {
+		select {
+		case <-cli.stopping:
+			return
+		default:
+		}
+		err := readInputFile(path, cli.InputFormat, topic, func(msg *kafka.Message) {
+			cli.processMessage(msg)
+			count++
+		})
+		if err != nil {
+			log.Printf("%v\n", err)
+		}
+	}
+	log.Printf("%d messages processed from %d files\n", count, len(cli.inputFiles))
+}
+
+// Interval to flush the output files and check if they have to be rotated.
+const fileSinkCheckInterval = 10 * time.Second
+
+// OutputRecord represents a record written by the file sink, where each line is a JSON record.
+// The value is embedded as it is when it is JSON, or as a JSON string otherwise (i.e. the InfluxDB line protocol), and null for tombstones.
+type OutputRecord struct {
+	Key     string          `json:"key,omitempty"`
+	Value   json.RawMessage `json:"value"`
+	Headers []OutputHeader  `json:"headers,omitempty"`
+}
+
+// OutputHeader represents a header of a record written by the file sink.
+type OutputHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func newOutputRecord(m *kafka.Message) *OutputRecord {
+	record := &OutputRecord{Key: string(m.Key)}
+	switch {
+	case m.Value == nil:
+	case json.Valid(m.Value):
+		record.Value = m.Value
+	default:
+		record.Value, _ = json.Marshal(string(m.Value))
+	}
+	for _, h := range m.Headers {
+		record.Headers = append(record.Headers, OutputHeader{Key: h.Key, Value: string(h.Value)})
+	}
+	return record
+}
+
+type sinkFile struct {
+	file    *os.File
+	writer  *bufio.Writer
+	size    int64
+	created time.Time
+}
+
+// FileSink writes the records to NDJSON files per topic instead of Kafka, rotating them by size and time.
+// Each line has the key, the value and the headers of a record (see OutputRecord).
+type FileSink struct {
+	Directory string
+	MaxSize   int64         // In bytes; zero means no limit
+	Interval  time.Duration // Zero means no time-based rotation
+	mutex     sync.Mutex
+	files     map[string]*sinkFile
+	sequence  int
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewFileSink creates a sink for a given directory, creating it if necessary.
+func NewFileSink(directory string, maxSize int64, interval time.Duration) (*FileSink, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create output directory %s: %v", directory, err)
+	}
+	return &FileSink{
+		Directory: directory,
+		MaxSize:   maxSize,
+		Interval:  interval,
+		files:     make(map[string]*sinkFile),
+	}, nil
+}
+
+// Start starts the background routine that flushes the files, and closes the ones older than the rotation interval.
+func (s *FileSink) Start() {
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(fileSinkCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.flush()
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// flush writes the buffered records to the files, closing the ones older than the rotation interval.
+func (s *FileSink) flush() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for topic, f := range s.files {
+		if s.Interval > 0 && time.Since(f.created) >= s.Interval {
+			if err := f.close(); err != nil {
+				log.Printf("cannot close file for %s: %v\n", topic, err)
+			}
+			delete(s.files, topic)
+		} else if err := f.writer.Flush(); err != nil {
+			log.Printf("cannot flush file for %s: %v\n", topic, err)
+		}
+	}
+}
+
+// Write appends a given message to the current file of its topic.
+func (s *FileSink) Write(m *kafka.Message) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(newOutputRecord(m)); err != nil {
+		return err
+	}
+	line := buf.Bytes()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	topic := *m.TopicPartition.Topic
+	f, ok := s.files[topic]
+	if ok && s.shouldRotate(f, len(line)) {
+		if err := f.close(); err != nil {
+			log.Printf("cannot close file for %s: %v\n", topic, err)
+		}
+		ok = false
+	}
+	if !ok {
+		var err error
+		if f, err = s.create(topic); err != nil {
+			return err
+		}
+		s.files[topic] = f
+	}
+	n, err := f.writer.Write(line)
+	f.size += int64(n)
+	return err
+}
+
+func (s *FileSink) shouldRotate(f *sinkFile, size int) bool {
+	if s.MaxSize > 0 && f.size > 0 && f.size+int64(size) > s.MaxSize {
+		return true
+	}
+	return s.Interval > 0 && time.Since(f.created) >= s.Interval
+}
+
+func (s *FileSink) create(topic string) (*sinkFile, error) {
+	now := time.Now()
+	s.sequence++
+	name := fmt.Sprintf("%s-%s-%d.ndjson", topic, now.UTC().Format("20060102T150405"), s.sequence)
+	file, err := os.Create(filepath.Join(s.Directory, name))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create output file: %v", err)
+	}
+	return &sinkFile{file: file, writer: bufio.NewWriter(file), created: now}, nil
+}
+
+func (f *sinkFile) close() error {
+	if err := f.writer.Flush(); err != nil {
+		f.file.Close()
+		return err
+	}
+	return f.file.Close()
+}
+
+// Close stops the background routine, and flushes and closes all the files.
+func (s *FileSink) Close() {
+	if s.done != nil {
+		close(s.done)
+		s.wg.Wait()
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for topic, f := range s.files {
+		if err := f.close(); err != nil {
+			log.Printf("cannot close file for %s: %v\n", topic, err)
+		}
+	}
+	s.files = make(map[string]*sinkFile)
+}
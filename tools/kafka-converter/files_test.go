@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"gotest.tools/assert"
+)
+
+func writeTestFile(t *testing.T, path string, data []byte) {
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadInputFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// Delimited
+	var buf bytes.Buffer
+	for _, value := range []string{"first", "second", ""} {
+		size := make([]byte, binary.MaxVarintLen64)
+		buf.Write(size[:binary.PutUvarint(size, uint64(len(value)))])
+		buf.WriteString(value)
+	}
+	writeTestFile(t, filepath.Join(dir, "messages.bin"), buf.Bytes())
+	values := make([]string, 0)
+	err := readInputFile(filepath.Join(dir, "messages.bin"), delimitedInput, "alarms", func(msg *kafka.Message) {
+		assert.Equal(t, "alarms", *msg.TopicPartition.Topic)
+		assert.Equal(t, kafka.Offset(len(values)), msg.TopicPartition.Offset)
+		assert.Assert(t, !msg.Timestamp.IsZero())
+		values = append(values, string(msg.Value))
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"first", "second", ""}, values)
+
+	// Truncated delimited
+	writeTestFile(t, filepath.Join(dir, "truncated.bin"), buf.Bytes()[:4])
+	err = readInputFile(filepath.Join(dir, "truncated.bin"), delimitedInput, "alarms", func(msg *kafka.Message) {})
+	assert.ErrorContains(t, err, "cannot read message at 0")
+
+	// Single
+	values = values[:0]
+	err = readInputFile(filepath.Join(dir, "messages.bin"), singleInput, "alarms", func(msg *kafka.Message) {
+		values = append(values, string(msg.Value))
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{buf.String()}, values)
+}
+
+func TestCaptureRecord(t *testing.T) {
+	topic := "alarms"
+	ts := time.Unix(1600000000, 123000000)
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 2, Offset: 10},
+		Key:            []byte("key"),
+		Value:          []byte{0x08, 0x01},
+		Timestamp:      ts,
+		Headers:        []kafka.Header{{Key: "source", Value: []byte("onms")}},
+	}
+	line, err := json.Marshal(newCaptureRecord(msg))
+	assert.NilError(t, err)
+	assert.Equal(t, `{"topic":"alarms","partition":2,"offset":10,"timestamp":1600000000123,"key":"a2V5","value":"CAE=","headers":[{"key":"source","value":"b25tcw=="}]}`, string(line))
+
+	// Tombstones are preserved
+	tombstone := `{"topic":"alarms","partition":0,"offset":11,"timestamp":1600000001000,"key":"a2V5","value":null}`
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "alarms.capture"), []byte(string(line)+"\n\n"+tombstone+"\n"))
+	messages := make([]*kafka.Message, 0)
+	err = readInputFile(filepath.Join(dir, "alarms.capture"), captureInput, "ignored", func(msg *kafka.Message) {
+		messages = append(messages, msg)
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(messages))
+	assert.Equal(t, "alarms", *messages[0].TopicPartition.Topic)
+	assert.Equal(t, int32(2), messages[0].TopicPartition.Partition)
+	assert.Equal(t, kafka.Offset(10), messages[0].TopicPartition.Offset)
+	assert.Equal(t, ts, messages[0].Timestamp)
+	assert.DeepEqual(t, msg.Value, messages[0].Value)
+	assert.DeepEqual(t, msg.Headers, messages[0].Headers)
+	assert.Assert(t, messages[1].Value == nil)
+
+	writeTestFile(t, filepath.Join(dir, "invalid.capture"), []byte("{}\nnot json\n"))
+	err = readInputFile(filepath.Join(dir, "invalid.capture"), captureInput, "", func(msg *kafka.Message) {})
+	assert.ErrorContains(t, err, "invalid capture record on line 2")
+}
+
+func TestExpandInputFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, "captures"), 0755)
+	for _, name := range []string{"b.bin", "a.bin", "captures/2.capture", "captures/1.capture"} {
+		writeTestFile(t, filepath.Join(dir, name), []byte{})
+	}
+	files, err := expandInputFiles(filepath.Join(dir, "*.bin") + "," + filepath.Join(dir, "captures"))
+	assert.NilError(t, err)
+	for i := range files {
+		files[i] = strings.TrimPrefix(files[i], dir+string(filepath.Separator))
+	}
+	assert.DeepEqual(t, []string{"a.bin", "b.bin", filepath.Join("captures", "1.capture"), filepath.Join("captures", "2.capture")}, files)
+
+	_, err = expandInputFiles(filepath.Join(dir, "*.missing"))
+	assert.ErrorContains(t, err, "no input files found")
+}
+
+func TestFileSink(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileSink(filepath.Join(dir, "output"), 120, 0)
+	assert.NilError(t, err)
+	alarms, metrics := "alarms-json", "metrics-influx"
+	headers := []kafka.Header{{Key: "ce_type", Value: []byte("org.opennms.alarm")}}
+	assert.NilError(t, s.Write(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &alarms}, Key: []byte("a<1>"), Value: []byte(`{"id":1}`), Headers: headers}))
+	assert.NilError(t, s.Write(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &alarms}, Key: []byte("a<1>")}))
+	assert.NilError(t, s.Write(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &metrics}, Value: []byte(`cpu,node=1 idle=99 1600000000000000000`)}))
+	// Exceeds the maximum size, so a new file is created
+	assert.NilError(t, s.Write(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &alarms}, Key: []byte("a2"), Value: []byte(`{"id":2,"uei":"uei.opennms.org/nodes/nodeDown"}`)}))
+	// Files older than the rotation interval are closed periodically
+	s.Interval = time.Nanosecond
+	s.flush()
+	assert.Equal(t, 0, len(s.files))
+	assert.NilError(t, s.Write(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &alarms}, Key: []byte("a3"), Value: []byte(`{"id":3}`)}))
+	s.Close()
+
+	list, err := ioutil.ReadDir(filepath.Join(dir, "output"))
+	assert.NilError(t, err)
+	content := make(map[string]string)
+	for _, f := range list {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "output", f.Name()))
+		assert.NilError(t, err)
+		assert.Assert(t, strings.HasSuffix(f.Name(), ".ndjson"))
+		content[f.Name()[strings.LastIndex(f.Name(), "-")+1:]] = string(data)
+	}
+	assert.DeepEqual(t, map[string]string{
+		"1.ndjson": `{"key":"a<1>","value":{"id":1},"headers":[{"key":"ce_type","value":"org.opennms.alarm"}]}` + "\n" + `{"key":"a<1>","value":null}` + "\n",
+		"2.ndjson": `{"value":"cpu,node=1 idle=99 1600000000000000000"}` + "\n",
+		"3.ndjson": `{"key":"a2","value":{"id":2,"uei":"uei.opennms.org/nodes/nodeDown"}}` + "\n",
+		"4.ndjson": `{"key":"a3","value":{"id":3}}` + "\n",
+	}, content)
+}
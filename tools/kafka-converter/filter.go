@@ -0,0 +1,896 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Filter represents a compiled filter expression.
+//
+// The language is a small subset of CEL evaluated against the decoded message, for instance:
+//
+//	severity >= MAJOR && uei.startsWith("uei.opennms.org/nodes/")
+//	"Servers" in category || node_criteria.foreign_source == "Production"
+//	has(last_event) && size(relatedAlarm) == 0
+//
+// Fields are referenced by their proto names, optionally prefixed by the message kind (i.e. alarm.severity).
+// Enum fields can be compared against numbers, enum names (i.e. MAJOR) or strings (i.e. "MAJOR").
+// Supported operators: ||, &&, !, ==, !=, <, <=, >, >=, in, and lists like ["a", "b"].
+// Supported functions: has(field), size(x), and the string methods startsWith, endsWith, contains and matches.
+type Filter struct {
+	Expression string
+	root       exprNode
+}
+
+// NewFilter compiles a filter expression.
+func NewFilter(expression string) (*Filter, error) {
+	p := &exprParser{}
+	if err := p.tokenize(expression); err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %v", expression, err)
+	}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %v", expression, err)
+	}
+	return &Filter{Expression: expression, root: root}, nil
+}
+
+// Check verifies that all the identifiers, fields and functions of the filter exist for a given message kind.
+func (f *Filter) Check(kind string) error {
+	ctx := &checkContext{kind: kind, root: proto.MessageReflect(newMessage(kind)).Descriptor()}
+	if _, err := f.root.check(ctx); err != nil {
+		return fmt.Errorf("invalid filter %q for %s messages: %v", f.Expression, kind, err)
+	}
+	return nil
+}
+
+// Match evaluates the filter against a given message of a given kind.
+func (f *Filter) Match(kind string, data proto.Message) (bool, error) {
+	ctx := &evalContext{kind: kind, root: proto.MessageReflect(data)}
+	result, err := f.root.eval(ctx)
+	if err != nil {
+		return false, err
+	}
+	b, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("filter %q doesn't evaluate to a boolean", f.Expression)
+	}
+	return b, nil
+}
+
+// Tokenizer
+
+type tokenKind int
+
+const (
+	identToken tokenKind = iota
+	numberToken
+	stringToken
+	opToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+type exprParser struct {
+	tokens []token
+	pos    int
+}
+
+var operators = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ",", "."}
+
+func (p *exprParser) tokenize(input string) error {
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			p.tokens = append(p.tokens, token{identToken, string(runes[start:i])})
+		case unicode.IsDigit(c) || (c == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			p.tokens = append(p.tokens, token{numberToken, string(runes[start:i])})
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != c; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i == len(runes) {
+				return fmt.Errorf("unterminated string")
+			}
+			i++
+			p.tokens = append(p.tokens, token{stringToken, sb.String()})
+		default:
+			found := false
+			for _, op := range operators {
+				if strings.HasPrefix(string(runes[i:]), op) {
+					p.tokens = append(p.tokens, token{opToken, op})
+					i += len([]rune(op))
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("unexpected character %q", c)
+			}
+		}
+	}
+	return nil
+}
+
+// Parser
+
+func (p *exprParser) peek(text string) bool {
+	if p.pos >= len(p.tokens) {
+		return false
+	}
+	t := p.tokens[p.pos]
+	return (t.kind == opToken || t.kind == identToken) && t.text == text
+}
+
+func (p *exprParser) expect(text string) error {
+	if !p.peek(text) {
+		if p.pos >= len(p.tokens) {
+			return fmt.Errorf("expected %q at the end", text)
+		}
+		return fmt.Errorf("expected %q, found %q", text, p.tokens[p.pos].text)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	for err == nil && p.peek("||") {
+		p.pos++
+		var right exprNode
+		if right, err = p.parseAnd(); err == nil {
+			left = &binaryNode{"||", left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseRelation()
+	for err == nil && p.peek("&&") {
+		p.pos++
+		var right exprNode
+		if right, err = p.parseRelation(); err == nil {
+			left = &binaryNode{"&&", left, right}
+		}
+	}
+	return left, err
+}
+
+func (p *exprParser) parseRelation() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "in"} {
+		if p.peek(op) {
+			p.pos++
+			right, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return &binaryNode{op, left, right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek("!") {
+		p.pos++
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *exprParser) parsePostfix() (exprNode, error) {
+	x, err := p.parsePrimary()
+	for err == nil && p.peek(".") {
+		p.pos++
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != identToken {
+			return nil, fmt.Errorf("expected identifier after '.'")
+		}
+		name := p.tokens[p.pos].text
+		p.pos++
+		if p.peek("(") {
+			var args []exprNode
+			if args, err = p.parseArgs(); err == nil {
+				call := &callNode{target: x, name: name, args: args}
+				err = call.compile()
+				x = call
+			}
+		} else {
+			x = &memberNode{x, name}
+		}
+	}
+	return x, err
+}
+
+func (p *exprParser) parseArgs() ([]exprNode, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	args := make([]exprNode, 0)
+	for !p.peek(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.pos++
+	return args, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	switch t.kind {
+	case numberToken:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", t.text)
+		}
+		return &literalNode{n}, nil
+	case stringToken:
+		return &literalNode{t.text}, nil
+	case identToken:
+		switch t.text {
+		case "true":
+			return &literalNode{true}, nil
+		case "false":
+			return &literalNode{false}, nil
+		case "null":
+			return &literalNode{nil}, nil
+		}
+		if p.peek("(") {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			return &callNode{name: t.text, args: args}, nil
+		}
+		return &identNode{t.text}, nil
+	}
+	switch t.text {
+	case "(":
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case "[":
+		items := make([]exprNode, 0)
+		for !p.peek("]") {
+			if len(items) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			item, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		p.pos++
+		return &listNode{items}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", t.text)
+}
+
+// Evaluation
+//
+// Values are represented as nil, bool, float64, string, []interface{}, enumValue or protoreflect.Message.
+
+type evalContext struct {
+	kind string
+	root protoreflect.Message
+}
+
+type enumValue struct {
+	desc   protoreflect.EnumDescriptor
+	number protoreflect.EnumNumber
+}
+
+func (e enumValue) name() string {
+	if v := e.desc.Values().ByNumber(e.number); v != nil {
+		return string(v.Name())
+	}
+	return ""
+}
+
+type exprNode interface {
+	eval(ctx *evalContext) (interface{}, error)
+	// check verifies the identifiers and fields against a message descriptor; returns the descriptor of the resulting value when it is a message.
+	check(ctx *checkContext) (protoreflect.MessageDescriptor, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(ctx *evalContext) (interface{}, error) {
+	return n.value, nil
+}
+
+type listNode struct {
+	items []exprNode
+}
+
+func (n *listNode) eval(ctx *evalContext) (interface{}, error) {
+	list := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		v, err := item.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+type identNode struct {
+	name string
+}
+
+func (n *identNode) eval(ctx *evalContext) (interface{}, error) {
+	if fd := ctx.root.Descriptor().Fields().ByName(protoreflect.Name(n.name)); fd != nil {
+		return fieldValue(ctx.root, fd), nil
+	}
+	if n.name == ctx.kind || n.name == "msg" {
+		return ctx.root, nil
+	}
+	if v, ok := findEnumValue(ctx.root.Descriptor().ParentFile(), n.name); ok {
+		return v, nil
+	}
+	return nil, fmt.Errorf("unknown identifier %s", n.name)
+}
+
+// findEnumValue looks for an enum value with the given name on all the enums of a given file.
+func findEnumValue(file protoreflect.FileDescriptor, name string) (enumValue, bool) {
+	var find func(enums protoreflect.EnumDescriptors, messages protoreflect.MessageDescriptors) (enumValue, bool)
+	find = func(enums protoreflect.EnumDescriptors, messages protoreflect.MessageDescriptors) (enumValue, bool) {
+		for i := 0; i < enums.Len(); i++ {
+			if v := enums.Get(i).Values().ByName(protoreflect.Name(name)); v != nil {
+				return enumValue{enums.Get(i), v.Number()}, true
+			}
+		}
+		for i := 0; i < messages.Len(); i++ {
+			if v, ok := find(messages.Get(i).Enums(), messages.Get(i).Messages()); ok {
+				return v, true
+			}
+		}
+		return enumValue{}, false
+	}
+	return find(file.Enums(), file.Messages())
+}
+
+type memberNode struct {
+	target exprNode
+	name   string
+}
+
+func (n *memberNode) message(ctx *evalContext) (protoreflect.Message, protoreflect.FieldDescriptor, error) {
+	target, err := n.target.eval(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	msg, ok := target.(protoreflect.Message)
+	if !ok {
+		return nil, nil, fmt.Errorf("cannot access field %s of a non-message value", n.name)
+	}
+	fd := msg.Descriptor().Fields().ByName(protoreflect.Name(n.name))
+	if fd == nil {
+		return nil, nil, fmt.Errorf("unknown field %s on %s", n.name, msg.Descriptor().Name())
+	}
+	return msg, fd, nil
+}
+
+func (n *memberNode) eval(ctx *evalContext) (interface{}, error) {
+	msg, fd, err := n.message(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fieldValue(msg, fd), nil
+}
+
+// fieldValue converts the value of a given field to one of the types supported by the filters.
+func fieldValue(msg protoreflect.Message, fd protoreflect.FieldDescriptor) interface{} {
+	v := msg.Get(fd)
+	if fd.IsList() {
+		list := v.List()
+		result := make([]interface{}, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			result[i] = scalarValue(fd, list.Get(i))
+		}
+		return result
+	}
+	return scalarValue(fd, v)
+}
+
+func scalarValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool()
+	case protoreflect.EnumKind:
+		return enumValue{fd.Enum(), v.Enum()}
+	case protoreflect.Int32Kind, protoreflect.Int64Kind, protoreflect.Sint32Kind, protoreflect.Sint64Kind, protoreflect.Sfixed32Kind, protoreflect.Sfixed64Kind:
+		return float64(v.Int())
+	case protoreflect.Uint32Kind, protoreflect.Uint64Kind, protoreflect.Fixed32Kind, protoreflect.Fixed64Kind:
+		return float64(v.Uint())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float()
+	case protoreflect.StringKind:
+		return v.String()
+	case protoreflect.BytesKind:
+		return string(v.Bytes())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return v.Message()
+	}
+	return nil
+}
+
+type notNode struct {
+	x exprNode
+}
+
+func (n *notNode) eval(ctx *evalContext) (interface{}, error) {
+	v, err := n.x.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("operator ! requires a boolean")
+	}
+	return !b, nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *binaryNode) eval(ctx *evalContext) (interface{}, error) {
+	left, err := n.left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s requires booleans", n.op)
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		right, err := n.right.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s requires booleans", n.op)
+		}
+		return r, nil
+	}
+	right, err := n.right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return valuesEqual(left, right), nil
+	case "!=":
+		return !valuesEqual(left, right), nil
+	case "in":
+		list, ok := right.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operator in requires a list")
+		}
+		for _, item := range list {
+			if valuesEqual(left, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	cmp, err := compareValues(left, right)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// normalize converts enums to numbers, or to strings when compared against strings.
+func normalize(a, b interface{}) (interface{}, interface{}) {
+	if e, ok := a.(enumValue); ok {
+		if s, ok := b.(string); ok {
+			if v := e.desc.Values().ByName(protoreflect.Name(s)); v != nil {
+				return float64(e.number), float64(v.Number())
+			}
+			return e.name(), s
+		}
+		a = float64(e.number)
+	}
+	if e, ok := b.(enumValue); ok {
+		if _, ok := a.(string); ok {
+			nb, na := normalize(b, a)
+			return na, nb
+		}
+		b = float64(e.number)
+	}
+	return a, b
+}
+
+func valuesEqual(a, b interface{}) bool {
+	a, b = normalize(a, b)
+	switch av := a.(type) {
+	case nil:
+		return b == nil
+	case bool, float64, string:
+		return a == b
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !valuesEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case protoreflect.Message:
+		bv, ok := b.(protoreflect.Message)
+		return ok && proto.Equal(proto.MessageV1(av.Interface()), proto.MessageV1(bv.Interface()))
+	}
+	return false
+}
+
+func compareValues(a, b interface{}) (int, error) {
+	a, b = normalize(a, b)
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			switch {
+			case av < bv:
+				return -1, nil
+			case av > bv:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %v with %v", a, b)
+}
+
+type callNode struct {
+	target exprNode
+	name   string
+	args   []exprNode
+	regex  *regexp.Regexp // Compiled at parse time when the argument of matches is a literal
+}
+
+// compile validates and compiles the regular expression of matches when it is a string literal.
+func (n *callNode) compile() error {
+	if n.name != "matches" || len(n.args) != 1 {
+		return nil
+	}
+	if lit, ok := n.args[0].(*literalNode); ok {
+		if pattern, ok := lit.value.(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid regular expression %s: %v", pattern, err)
+			}
+			n.regex = re
+		}
+	}
+	return nil
+}
+
+func (n *callNode) eval(ctx *evalContext) (interface{}, error) {
+	if n.target == nil && n.name == "has" {
+		return n.has(ctx)
+	}
+	args := make([]interface{}, 0, len(n.args)+1)
+	if n.target != nil {
+		target, err := n.target.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, target)
+	}
+	for _, arg := range n.args {
+		v, err := arg.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	switch n.name {
+	case "size":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("size requires one argument")
+		}
+		switch v := args[0].(type) {
+		case string:
+			return float64(len(v)), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		}
+		return nil, fmt.Errorf("size requires a string or a list")
+	case "startsWith", "endsWith", "contains", "matches":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s requires a target and one argument", n.name)
+		}
+		s, ok1 := args[0].(string)
+		arg, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("%s requires strings", n.name)
+		}
+		switch n.name {
+		case "startsWith":
+			return strings.HasPrefix(s, arg), nil
+		case "endsWith":
+			return strings.HasSuffix(s, arg), nil
+		case "contains":
+			return strings.Contains(s, arg), nil
+		default:
+			if n.regex != nil {
+				return n.regex.MatchString(s), nil
+			}
+			re, err := regexp.Compile(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %s: %v", arg, err)
+			}
+			return re.MatchString(s), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown function %s", n.name)
+}
+
+// has returns true if the field referenced by the argument is set.
+func (n *callNode) has(ctx *evalContext) (interface{}, error) {
+	if len(n.args) != 1 {
+		return nil, fmt.Errorf("has requires one argument")
+	}
+	var msg protoreflect.Message
+	var fd protoreflect.FieldDescriptor
+	switch arg := n.args[0].(type) {
+	case *identNode:
+		msg = ctx.root
+		fd = msg.Descriptor().Fields().ByName(protoreflect.Name(arg.name))
+		if fd == nil {
+			return nil, fmt.Errorf("unknown field %s", arg.name)
+		}
+	case *memberNode:
+		var err error
+		if msg, fd, err = arg.message(ctx); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("has requires a field")
+	}
+	return msg.Has(fd), nil
+}
+
+// Verification
+//
+// Expressions are verified against the descriptor of the message kind when loaded, so typos are not discovered on each message.
+
+type checkContext struct {
+	kind string
+	root protoreflect.MessageDescriptor
+}
+
+// fieldMessage returns the descriptor of a field that holds a single message, or nil otherwise.
+func fieldMessage(fd protoreflect.FieldDescriptor) protoreflect.MessageDescriptor {
+	if fd.IsList() || fd.IsMap() {
+		return nil
+	}
+	return fd.Message()
+}
+
+func (n *literalNode) check(ctx *checkContext) (protoreflect.MessageDescriptor, error) {
+	return nil, nil
+}
+
+func (n *listNode) check(ctx *checkContext) (protoreflect.MessageDescriptor, error) {
+	for _, item := range n.items {
+		if _, err := item.check(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (n *identNode) check(ctx *checkContext) (protoreflect.MessageDescriptor, error) {
+	if fd := ctx.root.Fields().ByName(protoreflect.Name(n.name)); fd != nil {
+		return fieldMessage(fd), nil
+	}
+	if n.name == ctx.kind || n.name == "msg" {
+		return ctx.root, nil
+	}
+	if _, ok := findEnumValue(ctx.root.ParentFile(), n.name); ok {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown identifier %s", n.name)
+}
+
+func (n *memberNode) check(ctx *checkContext) (protoreflect.MessageDescriptor, error) {
+	target, err := n.target.check(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, fmt.Errorf("cannot access field %s of a non-message value", n.name)
+	}
+	fd := target.Fields().ByName(protoreflect.Name(n.name))
+	if fd == nil {
+		return nil, fmt.Errorf("unknown field %s on %s", n.name, target.Name())
+	}
+	return fieldMessage(fd), nil
+}
+
+func (n *notNode) check(ctx *checkContext) (protoreflect.MessageDescriptor, error) {
+	_, err := n.x.check(ctx)
+	return nil, err
+}
+
+func (n *binaryNode) check(ctx *checkContext) (protoreflect.MessageDescriptor, error) {
+	if _, err := n.left.check(ctx); err != nil {
+		return nil, err
+	}
+	_, err := n.right.check(ctx)
+	return nil, err
+}
+
+func (n *callNode) check(ctx *checkContext) (protoreflect.MessageDescriptor, error) {
+	switch n.name {
+	case "has":
+		if n.target != nil || len(n.args) != 1 {
+			return nil, fmt.Errorf("has requires one argument")
+		}
+		if arg, ok := n.args[0].(*identNode); ok {
+			if ctx.root.Fields().ByName(protoreflect.Name(arg.name)) == nil {
+				return nil, fmt.Errorf("unknown field %s", arg.name)
+			}
+			return nil, nil
+		}
+		if _, ok := n.args[0].(*memberNode); !ok {
+			return nil, fmt.Errorf("has requires a field")
+		}
+	case "size", "startsWith", "endsWith", "contains", "matches":
+	default:
+		return nil, fmt.Errorf("unknown function %s", n.name)
+	}
+	if n.target != nil {
+		if _, err := n.target.check(ctx); err != nil {
+			return nil, err
+		}
+	}
+	for _, arg := range n.args {
+		if _, err := arg.check(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// filterCommand evaluates a filter expression against a sample message, either GPB or JSON.
+func filterCommand(args []string) error {
+	fs := flag.NewFlagSet("filter", flag.ExitOnError)
+	expression := fs.String("expression", "", "filter expression to evaluate")
+	kind := fs.String("message-kind", autoKind, "sample message kind; valid options: "+strings.Join(kinds, ", ")+" or "+autoKind+" to detect it (GPB only)")
+	descriptors := fs.String("descriptor-files", "", "optional CSV of FileDescriptorSet or .proto files with message types to use as message kinds")
+	file := fs.String("file", "", "file with the sample message; files ending with .json are parsed using the protobuf JSON mapping, otherwise as GPB")
+	fs.Parse(args)
+	if *file == "" {
+		return fmt.Errorf("sample file cannot be empty")
+	}
+	filter, err := NewFilter(*expression)
+	if err != nil {
+		return err
+	}
+	if *descriptors != "" {
+		if err := loadDescriptors(*descriptors); err != nil {
+			return err
+		}
+	}
+	payload, err := ioutil.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("cannot read sample file %s: %v", *file, err)
+	}
+	var data proto.Message
+	switch {
+	case strings.HasSuffix(*file, ".json"):
+		if !isValidKind(*kind) {
+			return fmt.Errorf("a valid message kind is required for JSON samples. Valid options: %s", strings.Join(kinds, ", "))
+		}
+		data = newMessage(*kind)
+		if err := protojson.Unmarshal(payload, proto.MessageV2(data)); err != nil {
+			return fmt.Errorf("invalid %s message: %v", *kind, err)
+		}
+	case *kind == autoKind:
+		if *kind, data, err = detectKind("", payload); err != nil {
+			return err
+		}
+	default:
+		if !isValidKind(*kind) {
+			return fmt.Errorf("invalid message kind %s. Valid options: %s or %s", *kind, strings.Join(kinds, ", "), autoKind)
+		}
+		data = newMessage(*kind)
+		if err := proto.Unmarshal(payload, data); err != nil {
+			return fmt.Errorf("invalid %s message: %v", *kind, err)
+		}
+	}
+	if err := filter.Check(*kind); err != nil {
+		return err
+	}
+	result, err := filter.Match(*kind, data)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s message: %v\n", *kind, result)
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"gotest.tools/assert"
+)
+
+func TestFilterAlarm(t *testing.T) {
+	alarm := &producer.Alarm{
+		Id:           1,
+		Uei:          "uei.opennms.org/nodes/nodeDown",
+		Severity:     producer.Severity_MAJOR,
+		NodeCriteria: &producer.NodeCriteria{Id: 10, ForeignSource: "Production", ForeignId: "srv01"},
+	}
+	tests := map[string]bool{
+		`severity >= MAJOR`:      true,
+		`severity >= "CRITICAL"`: false,
+		`alarm.severity == 5`:    true,
+		`severity == "MAJOR" && uei.startsWith("uei.opennms.org/nodes/")`:           true,
+		`uei in ["uei.opennms.org/nodes/nodeUp", "uei.opennms.org/nodes/nodeDown"]`: true,
+		`node_criteria.foreign_source == "Test" || id > 5`:                          false,
+		`!has(last_event) && has(node_criteria)`:                                    true,
+		`uei.matches("node(Up|Down)$") && size(relatedAlarm) == 0`:                  true,
+	}
+	for expression, expected := range tests {
+		f, err := NewFilter(expression)
+		assert.NilError(t, err, expression)
+		result, err := f.Match(alarmKind, alarm)
+		assert.NilError(t, err, expression)
+		assert.Equal(t, expected, result, expression)
+	}
+}
+
+func TestFilterNode(t *testing.T) {
+	node := &producer.Node{Id: 1, Label: "srv01", Category: []string{"Servers", "Production"}}
+	f, err := NewFilter(`"Servers" in category && label.contains("srv")`)
+	assert.NilError(t, err)
+	result, err := f.Match(nodeKind, node)
+	assert.NilError(t, err)
+	assert.Assert(t, result)
+
+	f, err = NewFilter(`unknown == 1`)
+	assert.NilError(t, err)
+	_, err = f.Match(nodeKind, node)
+	assert.ErrorContains(t, err, "unknown identifier")
+}
+
+func TestCheckFilter(t *testing.T) {
+	for _, expression := range []string{`severity >= MAJOR`, `alarm.node_criteria.foreign_source == "Test"`, `has(last_event) && last_event.uei.contains("node")`, `size(relatedAlarm) == 0`} {
+		f, err := NewFilter(expression)
+		assert.NilError(t, err, expression)
+		assert.NilError(t, f.Check(alarmKind), expression)
+	}
+	tests := map[string]string{
+		`unknown == 1`:                      "unknown identifier unknown",
+		`node_criteria.foreign == "Test"`:   "unknown field foreign on NodeCriteria",
+		`uei.length == 1`:                   "cannot access field length of a non-message value",
+		`has(unknown)`:                      "unknown field unknown",
+		`uei.startWith("uei.")`:             "unknown function startWith",
+		`node.label == "srv01"`:             "unknown identifier node",
+		`!(id > 1 && last_event.foo == "")`: "unknown field foo on Event",
+	}
+	for expression, expected := range tests {
+		f, err := NewFilter(expression)
+		assert.NilError(t, err, expression)
+		assert.ErrorContains(t, f.Check(alarmKind), expected, expression)
+	}
+}
+
+func TestInvalidFilter(t *testing.T) {
+	for _, expression := range []string{`severity >=`, `(id == 1`, `uei.startsWith("a"`, `id == 1 id`, `"unterminated`, `uei.matches("node(Up")`} {
+		_, err := NewFilter(expression)
+		assert.ErrorContains(t, err, "invalid filter", expression)
+	}
+}
+
+func TestRouteFilters(t *testing.T) {
+	route := &Route{
+		SourceTopic: "OpenNMS-alarms",
+		MessageKind: alarmKind,
+		DestTopics:  []string{"alarms-json", "major-alarms-json"},
+		Filter:      `uei.startsWith("uei.opennms.org/")`,
+		Filters:     map[string]string{"major-alarms-json": `severity >= MAJOR`},
+	}
+	assert.NilError(t, route.validate())
+	alarm := &producer.Alarm{Uei: "uei.opennms.org/test", Severity: producer.Severity_MINOR}
+	assert.Assert(t, route.accepts("alarms-json", alarmKind, alarm))
+	assert.Assert(t, !route.accepts("major-alarms-json", alarmKind, alarm))
+	alarm.Uei = "uei.other/test"
+	assert.Assert(t, !route.accepts("alarms-json", alarmKind, alarm))
+
+	route.Filters = map[string]string{"unknown-topic": `severity >= MAJOR`}
+	assert.ErrorContains(t, route.validate(), "unknown destination")
+
+	// Fields are verified against the kind of the route, or against any kind with auto
+	route.Filters = map[string]string{"major-alarms-json": `label == "srv01"`}
+	assert.ErrorContains(t, route.validate(), "unknown identifier label")
+	route.MessageKind = autoKind
+	assert.NilError(t, route.validate())
+	route.Filters = map[string]string{"major-alarms-json": `unknown == 1`}
+	assert.ErrorContains(t, route.validate(), "don't exist on any message kind")
+}
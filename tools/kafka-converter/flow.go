@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+
+	"github.com/agalue/kafka-converter/api/flows"
+)
+
+// Names of the most common IP protocols, based on the IANA assigned numbers.
+var ipProtocols = map[uint32]string{
+	1:   "ICMP",
+	2:   "IGMP",
+	4:   "IPIP",
+	6:   "TCP",
+	17:  "UDP",
+	41:  "IPv6",
+	47:  "GRE",
+	50:  "ESP",
+	51:  "AH",
+	58:  "ICMPv6",
+	88:  "EIGRP",
+	89:  "OSPF",
+	103: "PIM",
+	112: "VRRP",
+	132: "SCTP",
+}
+
+// FlowSummary represents the fields derived from a flow document.
+type FlowSummary struct {
+	ProtocolName   string
+	Duration       uint64 // In milliseconds
+	BytesPerSecond float64
+}
+
+// summarizeFlow returns the fields derived from a given flow document; fields that cannot be derived are left empty.
+func summarizeFlow(flow *flows.FlowDocument) FlowSummary {
+	s := FlowSummary{}
+	if flow.Protocol != nil {
+		s.ProtocolName = ipProtocols[flow.Protocol.Value]
+	}
+	first, last := flow.GetFirstSwitched().GetValue(), flow.GetLastSwitched().GetValue()
+	if first > 0 && last >= first {
+		s.Duration = last - first
+	}
+	if s.Duration > 0 && flow.NumBytes != nil {
+		s.BytesPerSecond = float64(flow.NumBytes.Value) * 1000 / float64(s.Duration)
+	}
+	return s
+}
+
+// readableIP returns the canonical representation of an IP address (i.e. ::1 instead of 0:0:0:0:0:0:0:1),
+// or the address as it is if it cannot be parsed.
+func readableIP(address string) string {
+	if ip := net.ParseIP(address); ip != nil {
+		return ip.String()
+	}
+	return address
+}
+
+// addFlowFields adds the derived fields at the end of the JSON representation of a flow document, preserving the order of the other keys.
+// The IP addresses are normalized, and with the legacy format, the wrapper types are rendered as plain values like protojson does.
+func (e *JSONEncoder) addFlowFields(jsonBytes []byte, flow *flows.FlowDocument) ([]byte, error) {
+	name := func(snake, camel string) string {
+		if e.Format == camelJSONFormat {
+			return camel
+		}
+		return snake
+	}
+	addresses := map[string]bool{
+		name("src_address", "srcAddress"):          true,
+		name("dst_address", "dstAddress"):          true,
+		name("next_hop_address", "nextHopAddress"): true,
+	}
+	rewrite := func(key string, value json.RawMessage) ([]byte, error) {
+		if e.Format == legacyJSONFormat && value[0] == '{' {
+			var wrapper map[string]json.RawMessage
+			if err := json.Unmarshal(value, &wrapper); err == nil && len(wrapper) == 1 {
+				if v, ok := wrapper["value"]; ok {
+					return v, nil
+				}
+			}
+		}
+		if addresses[key] {
+			var address string
+			if err := json.Unmarshal(value, &address); err == nil {
+				return json.Marshal(readableIP(address))
+			}
+		}
+		return value, nil
+	}
+	s := summarizeFlow(flow)
+	extra := make([]jsonField, 0)
+	if s.ProtocolName != "" {
+		extra = append(extra, jsonField{name("protocol_name", "protocolName"), s.ProtocolName})
+	}
+	if s.Duration > 0 {
+		extra = append(extra,
+			jsonField{name("duration_ms", "durationMs"), s.Duration},
+			jsonField{name("bytes_per_second", "bytesPerSecond"), s.BytesPerSecond},
+		)
+	}
+	var buf bytes.Buffer
+	if err := rewriteObject(&buf, jsonBytes, rewrite, extra...); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/agalue/kafka-converter/api/flows"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"gotest.tools/assert"
+)
+
+func buildFlow() *flows.FlowDocument {
+	return &flows.FlowDocument{
+		Timestamp:      1600000010000,
+		NumBytes:       wrapperspb.UInt64(50000),
+		Direction:      flows.Direction_INGRESS,
+		SrcAddress:     "0:0:0:0:0:0:0:1",
+		DstAddress:     "10.0.0.2",
+		DstPort:        wrapperspb.UInt32(443),
+		FirstSwitched:  wrapperspb.UInt64(1600000000000),
+		LastSwitched:   wrapperspb.UInt64(1600000010000),
+		Protocol:       wrapperspb.UInt32(6),
+		NetflowVersion: flows.NetflowVersion_IPFIX,
+	}
+}
+
+func TestSummarizeFlow(t *testing.T) {
+	s := summarizeFlow(buildFlow())
+	assert.Equal(t, "TCP", s.ProtocolName)
+	assert.Equal(t, uint64(10000), s.Duration)
+	assert.Equal(t, float64(5000), s.BytesPerSecond)
+
+	s = summarizeFlow(&flows.FlowDocument{Protocol: wrapperspb.UInt32(253), NumBytes: wrapperspb.UInt64(100)})
+	assert.Equal(t, "", s.ProtocolName)
+	assert.Equal(t, uint64(0), s.Duration)
+	assert.Equal(t, float64(0), s.BytesPerSecond)
+}
+
+func TestFlowJSON(t *testing.T) {
+	tests := []struct {
+		encoder  *JSONEncoder
+		expected string
+	}{
+		{
+			&JSONEncoder{Format: legacyJSONFormat},
+			`{"timestamp":1600000010000,"num_bytes":50000,"dst_address":"10.0.0.2","dst_port":443,"first_switched":1600000000000,"last_switched":1600000010000,"protocol":6,"src_address":"::1","netflow_version":2,"protocol_name":"TCP","duration_ms":10000,"bytes_per_second":5000}`,
+		},
+		{
+			&JSONEncoder{Format: protoJSONFormat, EnumsAsStrings: true},
+			`{"timestamp":"1600000010000","num_bytes":"50000","dst_address":"10.0.0.2","dst_port":443,"first_switched":"1600000000000","last_switched":"1600000010000","protocol":6,"src_address":"::1","netflow_version":"IPFIX","protocol_name":"TCP","duration_ms":10000,"bytes_per_second":5000}`,
+		},
+		{
+			&JSONEncoder{Format: camelJSONFormat, EnumsAsStrings: true, TimesAsRFC3339: true},
+			`{"timestamp":"2020-09-13T12:26:50.000Z","numBytes":"50000","dstAddress":"10.0.0.2","dstPort":443,"firstSwitched":"2020-09-13T12:26:40.000Z","lastSwitched":"2020-09-13T12:26:50.000Z","protocol":6,"srcAddress":"::1","netflowVersion":"IPFIX","protocolName":"TCP","durationMs":10000,"bytesPerSecond":5000}`,
+		},
+	}
+	// The derived fields are appended, preserving the order of the fields of the message
+	for _, test := range tests {
+		data, err := test.encoder.Marshal(buildFlow())
+		assert.NilError(t, err)
+		assert.Equal(t, test.expected, string(data))
+	}
+}
@@ -5,6 +5,10 @@ go 1.15
 require (
 	github.com/confluentinc/confluent-kafka-go v1.7.0
 	github.com/golang/protobuf v1.5.2
+	github.com/golang/snappy v0.0.4
 	github.com/jeremywohl/flatten v1.0.1
+	github.com/xitongsys/parquet-go v1.6.2
+	github.com/xitongsys/parquet-go-source v0.0.0-20200817004010-026bad9b25d0
 	google.golang.org/protobuf v1.27.1
+	gotest.tools v2.2.0+incompatible
 )
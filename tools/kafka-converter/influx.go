@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+)
+
+// Valid options for the handling of the string attributes with InfluxDB.
+const (
+	influxStringAsTag   = "tag"
+	influxStringAsField = "field"
+)
+
+// Prefix for the string attributes named like the identity tags (i.e. node_id), so they don't overwrite them.
+const influxAttributePrefix = "attr_"
+
+// The tags with the resolved identity of the resources.
+var influxIdentityTags = map[string]bool{
+	"node_id":        true,
+	"foreign_source": true,
+	"foreign_id":     true,
+	"node_label":     true,
+	"location":       true,
+	"resource_type":  true,
+	"instance":       true,
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+	fieldStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+)
+
+// InfluxConverter renders CollectionSet messages using the InfluxDB line protocol.
+type InfluxConverter struct {
+	StringsAsTags bool
+}
+
+// Convert returns the line protocol representation of the collection set, with one line per group of numeric attributes of each resource.
+// Non-finite values (NaN and infinity) are not supported by the line protocol, so they are skipped.
+func (c *InfluxConverter) Convert(cs *producer.CollectionSet) []string {
+	result := make([]string, 0, len(cs.Resource))
+	timestamp := strconv.FormatInt(cs.Timestamp*int64(time.Millisecond), 10)
+	for _, resource := range cs.Resource {
+		if len(resource.Numeric) == 0 {
+			continue
+		}
+		tags := resolveResource(resource).Labels()
+		stringFields := make([]string, 0)
+		for _, attr := range resource.String_ {
+			if c.StringsAsTags {
+				name := attr.Name
+				if influxIdentityTags[name] {
+					name = influxAttributePrefix + name
+				}
+				if attr.Value != "" {
+					tags[name] = attr.Value
+				}
+			} else {
+				stringFields = append(stringFields, tagEscaper.Replace(attr.Name)+`="`+fieldStringEscaper.Replace(attr.Value)+`"`)
+			}
+		}
+		tagSet := influxTagSet(tags)
+
+		// Group numeric attributes by measurement, keeping the original order
+		groups := make([]string, 0)
+		fields := make(map[string][]string)
+		for _, attr := range resource.Numeric {
+			if math.IsNaN(attr.Value) || math.IsInf(attr.Value, 0) {
+				continue
+			}
+			if _, ok := fields[attr.Group]; !ok {
+				groups = append(groups, attr.Group)
+			}
+			fields[attr.Group] = append(fields[attr.Group], tagEscaper.Replace(attr.Name)+"="+strconv.FormatFloat(attr.Value, 'g', -1, 64))
+		}
+		for _, group := range groups {
+			fieldSet := strings.Join(append(fields[group], stringFields...), ",")
+			result = append(result, measurementEscaper.Replace(group)+tagSet+" "+fieldSet+" "+timestamp)
+		}
+	}
+	return result
+}
+
+func influxTagSet(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString("," + tagEscaper.Replace(k) + "=" + tagEscaper.Replace(tags[k]))
+	}
+	return sb.String()
+}
+
+// InfluxWriter sends line protocol data to the InfluxDB v2 HTTP API in batches.
+type InfluxWriter struct {
+	*Batcher
+	URL    string
+	Token  string
+	client *http.Client
+}
+
+// NewInfluxWriter creates a new writer for the given URL, for instance:
+// http://influxdb:8086/api/v2/write?org=opennms&bucket=metrics
+func NewInfluxWriter(url, token string, batchSize int, flushInterval time.Duration) *InfluxWriter {
+	w := &InfluxWriter{
+		URL:    url,
+		Token:  token,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	w.Batcher = NewBatcher("InfluxDB", batchSize, flushInterval, func(items []interface{}) error {
+		lines := make([]string, len(items))
+		for i, item := range items {
+			lines[i] = item.(string)
+		}
+		return w.send(lines)
+	})
+	return w
+}
+
+// Add appends the given lines to the pending ones, queuing the full batches.
+func (w *InfluxWriter) Add(lines []string) {
+	items := make([]interface{}, len(lines))
+	for i, line := range lines {
+		items[i] = line
+	}
+	w.Batcher.Add(items...)
+}
+
+// send posts the given lines to InfluxDB.
+func (w *InfluxWriter) send(lines []string) error {
+	body := strings.Join(lines, "\n")
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.Token != "" {
+		req.Header.Set("Authorization", "Token "+w.Token)
+	}
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("server returned HTTP status %s: %s", res.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
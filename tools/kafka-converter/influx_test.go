@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"gotest.tools/assert"
+)
+
+func TestInfluxConverter(t *testing.T) {
+	cs := &producer.CollectionSet{
+		Timestamp: 1600000000000,
+		Resource: []*producer.CollectionSetResource{
+			{
+				Resource: &producer.CollectionSetResource_Generic{
+					Generic: &producer.GenericTypeResource{
+						Node:     &producer.NodeLevelResource{NodeId: 1, NodeLabel: "srv 01"},
+						Type:     "hrStorageIndex",
+						Instance: "1",
+					},
+				},
+				String_: []*producer.StringAttribute{{Name: "hrStorageDescr", Value: "Physical memory"}},
+				Numeric: []*producer.NumericAttribute{
+					{Group: "mib2-host-resources-storage", Name: "hrStorageSize", Value: 1024},
+					{Group: "mib2-host-resources-storage", Name: "hrStorageUsed", Value: 512},
+				},
+			},
+			{
+				Resource: &producer.CollectionSetResource_Response{
+					Response: &producer.ResponseTimeResource{Instance: "10.0.0.1", Location: "Default"},
+				},
+				Numeric: []*producer.NumericAttribute{{Group: "icmp", Name: "icmp", Value: 0.5}},
+			},
+		},
+	}
+
+	c := &InfluxConverter{StringsAsTags: true}
+	lines := c.Convert(cs)
+	assert.Equal(t, 2, len(lines))
+	assert.Equal(t, `mib2-host-resources-storage,hrStorageDescr=Physical\ memory,instance=1,node_id=1,node_label=srv\ 01,resource_type=hrStorageIndex hrStorageSize=1024,hrStorageUsed=512 1600000000000000000`, lines[0])
+	assert.Equal(t, `icmp,instance=10.0.0.1,location=Default,resource_type=responseTime icmp=0.5 1600000000000000000`, lines[1])
+
+	// String attributes named like identity tags don't overwrite them
+	cs.Resource[0].String_ = append(cs.Resource[0].String_, &producer.StringAttribute{Name: "node_label", Value: "other"})
+	lines = c.Convert(cs)
+	assert.Equal(t, `mib2-host-resources-storage,attr_node_label=other,hrStorageDescr=Physical\ memory,instance=1,node_id=1,node_label=srv\ 01,resource_type=hrStorageIndex hrStorageSize=1024,hrStorageUsed=512 1600000000000000000`, lines[0])
+	cs.Resource[0].String_ = cs.Resource[0].String_[:1]
+
+	c = &InfluxConverter{StringsAsTags: false}
+	lines = c.Convert(cs)
+	assert.Equal(t, `mib2-host-resources-storage,instance=1,node_id=1,node_label=srv\ 01,resource_type=hrStorageIndex hrStorageSize=1024,hrStorageUsed=512,hrStorageDescr="Physical memory" 1600000000000000000`, lines[0])
+
+	// One line per group, skipping the non-finite values
+	cs.Resource[0].Numeric = append(cs.Resource[0].Numeric,
+		&producer.NumericAttribute{Group: "mib2-host-resources-storage", Name: "hrStorageAllocationUnits", Value: math.NaN()},
+		&producer.NumericAttribute{Group: "extra", Name: "ratio", Value: math.Inf(1)},
+		&producer.NumericAttribute{Group: "other", Name: "value", Value: 1},
+	)
+	lines = c.Convert(cs)
+	assert.Equal(t, 3, len(lines))
+	assert.Equal(t, `mib2-host-resources-storage,instance=1,node_id=1,node_label=srv\ 01,resource_type=hrStorageIndex hrStorageSize=1024,hrStorageUsed=512,hrStorageDescr="Physical memory" 1600000000000000000`, lines[0])
+	assert.Equal(t, `other,instance=1,node_id=1,node_label=srv\ 01,resource_type=hrStorageIndex value=1,hrStorageDescr="Physical memory" 1600000000000000000`, lines[1])
+}
+
+func TestInfluxWriter(t *testing.T) {
+	bodies := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Token secret", r.Header.Get("Authorization"))
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NilError(t, err)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := NewInfluxWriter(server.URL, "secret", 3, time.Minute)
+	w.Add([]string{"cpu idle=99 1", "cpu idle=98 2"})
+	assert.Equal(t, 0, len(w.queue))
+	w.Add([]string{"cpu idle=97 3", "cpu idle=96 4"})
+	assert.Equal(t, 1, len(w.queue))
+	assert.Equal(t, 1, len(w.pending))
+	assert.Equal(t, 0, len(bodies))
+
+	w.Stop()
+	assert.DeepEqual(t, []string{
+		strings.Join([]string{"cpu idle=99 1", "cpu idle=98 2", "cpu idle=97 3"}, "\n"),
+		"cpu idle=96 4",
+	}, bodies)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/flows"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Valid JSON formats.
+const (
+	legacyJSONFormat = "legacy" // encoding/json using the generated struct tags
+	protoJSONFormat  = "proto"  // canonical protobuf JSON mapping using the proto field names
+	camelJSONFormat  = "camel"  // canonical protobuf JSON mapping using lowerCamelCase names
+)
+
+var jsonFormats = []string{legacyJSONFormat, protoJSONFormat, camelJSONFormat}
+
+// Layout used when converting timestamps to strings (RFC3339 with milliseconds).
+const rfc3339Millis = "2006-01-02T15:04:05.000Z07:00"
+
+// Fields from the OpenNMS Producer messages with milliseconds since epoch, for all the supported formats.
+var timestampFields = map[string]bool{
+	"time":             true,
+	"timestamp":        true,
+	"create_time":      true,
+	"createTime":       true,
+	"first_event_time": true,
+	"firstEventTime":   true,
+	"last_event_time":  true,
+	"lastEventTime":    true,
+	"ack_time":         true,
+	"ackTime":          true,
+	"window_start":     true,
+	"window_end":       true,
+	"first_switched":   true,
+	"firstSwitched":    true,
+	"last_switched":    true,
+	"lastSwitched":     true,
+	"delta_switched":   true,
+	"deltaSwitched":    true,
+}
+
+// Fields with nested messages whose timestamps are also converted; any other nested object (i.e. user data) is left as is.
+var timestampMessages = map[string]bool{
+	"last_event":   true,
+	"lastEvent":    true,
+	"relatedAlarm": true,
+}
+
+// JSONEncoder converts GPB messages to JSON.
+type JSONEncoder struct {
+	Format         string
+	EnumsAsStrings bool
+	TimesAsRFC3339 bool
+}
+
+func (e *JSONEncoder) validate() error {
+	if contains(jsonFormats, e.Format) {
+		return nil
+	}
+	return fmt.Errorf("invalid JSON format %s", e.Format)
+}
+
+// Marshal returns the JSON representation of a given message.
+func (e *JSONEncoder) Marshal(data proto.Message) ([]byte, error) {
+	if msg, ok := data.(*dynamicpb.Message); ok {
+		jsonBytes, err := e.marshalDynamic(msg)
+		if err == nil && e.TimesAsRFC3339 {
+			return convertTimestamps(jsonBytes)
+		}
+		return jsonBytes, err
+	}
+	var jsonBytes []byte
+	var err error
+	switch e.Format {
+	case protoJSONFormat, camelJSONFormat:
+		opts := protojson.MarshalOptions{
+			UseProtoNames:  e.Format == protoJSONFormat,
+			UseEnumNumbers: !e.EnumsAsStrings,
+		}
+		if jsonBytes, err = opts.Marshal(proto.MessageV2(data)); err != nil {
+			return nil, err
+		}
+		// protojson output is deliberately unstable; make it compact to have a predictable result
+		var buf bytes.Buffer
+		if err = json.Compact(&buf, jsonBytes); err != nil {
+			return nil, err
+		}
+		jsonBytes = buf.Bytes()
+	default:
+		if jsonBytes, err = json.Marshal(data); err != nil {
+			return nil, err
+		}
+	}
+	if flow, ok := data.(*flows.FlowDocument); ok {
+		if jsonBytes, err = e.addFlowFields(jsonBytes, flow); err != nil {
+			return nil, err
+		}
+	}
+	if e.TimesAsRFC3339 {
+		return convertTimestamps(jsonBytes)
+	}
+	return jsonBytes, nil
+}
+
+// convertTimestamps replaces the milliseconds since epoch with RFC3339 strings for the well-known timestamp fields.
+// Only the root message and the nested messages from timestampMessages are converted, and the order of the keys is preserved.
+func convertTimestamps(jsonBytes []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := walkTimestamps(&buf, bytes.TrimSpace(jsonBytes), true); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func walkTimestamps(buf *bytes.Buffer, raw json.RawMessage, message bool) error {
+	if len(raw) == 0 || (raw[0] != '{' && raw[0] != '[') {
+		buf.Write(raw)
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	isObject := raw[0] == '{'
+	buf.WriteByte(raw[0])
+	for i := 0; decoder.More(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		nested := message
+		var key string
+		if isObject {
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			key, _ = token.(string)
+			keyBytes, _ := json.Marshal(key)
+			buf.Write(keyBytes)
+			buf.WriteByte(':')
+			nested = message && timestampMessages[key]
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		if isObject && message && timestampFields[key] {
+			if ts, ok := rawToRFC3339(value); ok {
+				buf.WriteString(`"` + ts + `"`)
+				continue
+			}
+		}
+		if err := walkTimestamps(buf, value, nested); err != nil {
+			return err
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	if isObject {
+		buf.WriteByte('}')
+	} else {
+		buf.WriteByte(']')
+	}
+	return nil
+}
+
+// jsonField is a key-value pair of a JSON object.
+type jsonField struct {
+	key   string
+	value interface{}
+}
+
+// rewriteObject copies a JSON object preserving the order of the keys, replacing each value with the result of a given function,
+// and appending the given fields at the end.
+func rewriteObject(buf *bytes.Buffer, raw json.RawMessage, rewrite func(key string, value json.RawMessage) ([]byte, error), extra ...jsonField) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("expected a JSON object")
+	}
+	count := 0
+	write := func(key string, value []byte) {
+		if count > 0 {
+			buf.WriteByte(',')
+		}
+		keyBytes, _ := json.Marshal(key)
+		buf.Write(keyBytes)
+		buf.WriteByte(':')
+		buf.Write(value)
+		count++
+	}
+	buf.WriteByte('{')
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, _ := token.(string)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		rewritten, err := rewrite(key, value)
+		if err != nil {
+			return err
+		}
+		write(key, rewritten)
+	}
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	for _, field := range extra {
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return err
+		}
+		write(field.key, value)
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func rawToRFC3339(raw json.RawMessage) (string, bool) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return "", false
+	}
+	return toRFC3339(value)
+}
+
+// toRFC3339 converts a JSON number, or a string with a number (as protojson does with 64-bit integers), to a timestamp string.
+func toRFC3339(value interface{}) (string, bool) {
+	var text string
+	switch v := value.(type) {
+	case json.Number:
+		text = v.String()
+	case string:
+		text = v
+	default:
+		return "", false
+	}
+	ms, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return "", false
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC().Format(rfc3339Millis), true
+}
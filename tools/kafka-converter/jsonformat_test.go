@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"gotest.tools/assert"
+)
+
+func TestJSONEncoder(t *testing.T) {
+	alarm := &producer.Alarm{
+		Id:             1,
+		Uei:            "uei.opennms.org/test",
+		Severity:       producer.Severity_MAJOR,
+		FirstEventTime: 1600000000000,
+		NodeCriteria:   &producer.NodeCriteria{Id: 1},
+	}
+	tests := []struct {
+		encoder  JSONEncoder
+		expected string
+	}{
+		{
+			JSONEncoder{Format: legacyJSONFormat},
+			`{"id":1,"uei":"uei.opennms.org/test","node_criteria":{"id":1},"severity":5,"first_event_time":1600000000000}`,
+		},
+		{
+			JSONEncoder{Format: legacyJSONFormat, TimesAsRFC3339: true},
+			`{"id":1,"uei":"uei.opennms.org/test","node_criteria":{"id":1},"severity":5,"first_event_time":"2020-09-13T12:26:40.000Z"}`,
+		},
+		{
+			JSONEncoder{Format: protoJSONFormat, EnumsAsStrings: true},
+			`{"id":"1","uei":"uei.opennms.org/test","node_criteria":{"id":"1"},"severity":"MAJOR","first_event_time":"1600000000000"}`,
+		},
+		{
+			JSONEncoder{Format: camelJSONFormat, TimesAsRFC3339: true},
+			`{"id":"1","uei":"uei.opennms.org/test","nodeCriteria":{"id":"1"},"severity":5,"firstEventTime":"2020-09-13T12:26:40.000Z"}`,
+		},
+	}
+	for _, test := range tests {
+		jsonBytes, err := test.encoder.Marshal(alarm)
+		assert.NilError(t, err)
+		assert.Equal(t, test.expected, string(jsonBytes))
+	}
+}
+
+func TestConvertTimestamps(t *testing.T) {
+	// Nested messages are converted, but not user data with the same keys
+	input := `{"uei":"test","time":1600000000000,"last_event":{"time":"1600000000000","parameter":[{"name":"time","value":"1"}]},` +
+		`"relatedAlarm":[{"ack_time":1600000000000}],"strings":{"timestamp":"1600000000000"},"timestamp":"now"}`
+	output, err := convertTimestamps([]byte(input))
+	assert.NilError(t, err)
+	assert.Equal(t, `{"uei":"test","time":"2020-09-13T12:26:40.000Z","last_event":{"time":"2020-09-13T12:26:40.000Z","parameter":[{"name":"time","value":"1"}]},`+
+		`"relatedAlarm":[{"ack_time":"2020-09-13T12:26:40.000Z"}],"strings":{"timestamp":"1600000000000"},"timestamp":"now"}`, string(output))
+
+	_, err = convertTimestamps([]byte(`{"time":`))
+	assert.Assert(t, err != nil)
+}
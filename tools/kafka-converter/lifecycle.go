@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/proto"
+)
+
+// Types of alarm lifecycle transitions.
+const (
+	alarmCreated        = "created"
+	alarmEscalated      = "escalated"
+	alarmDeescalated    = "deescalated"
+	alarmCleared        = "cleared"
+	alarmAcknowledged   = "acknowledged"
+	alarmUnacknowledged = "unacknowledged"
+	alarmCountIncreased = "count_incremented"
+	alarmDeleted        = "deleted"
+)
+
+// AlarmTransition represents a meaningful change between two snapshots of an alarm.
+type AlarmTransition struct {
+	Type         string      `json:"type"`
+	Key          string      `json:"key"`
+	AlarmID      uint64      `json:"alarm_id"`
+	ReductionKey string      `json:"reduction_key,omitempty"`
+	UEI          string      `json:"uei,omitempty"`
+	Time         uint64      `json:"time"`
+	Before       interface{} `json:"before,omitempty"`
+	After        interface{} `json:"after,omitempty"`
+}
+
+// AlarmTracker keeps the last snapshot of each alarm, indexed by the Kafka record key, to derive lifecycle transitions.
+type AlarmTracker struct {
+	Topics []string
+	mutex  sync.Mutex
+	alarms map[string]*producer.Alarm
+}
+
+// NewAlarmTracker creates an empty tracker for the given alarm topics.
+func NewAlarmTracker(topics []string) *AlarmTracker {
+	return &AlarmTracker{Topics: topics, alarms: make(map[string]*producer.Alarm)}
+}
+
+// Start rebuilds the snapshots from the alarm topics, up to the given offsets (i.e. the committed offsets of the consumer group),
+// so the transitions of the messages not yet processed are emitted once.
+func (t *AlarmTracker) Start(config *kafka.ConfigMap, until func(partitions []kafka.TopicPartition) ([]kafka.TopicPartition, error)) error {
+	for _, topic := range t.Topics {
+		reader := &TopicReader{Topic: topic, Handler: t.restore, Until: until}
+		if err := reader.Start(config); err != nil {
+			return err
+		}
+	}
+	log.Printf("alarm tracker initialized with %d alarms\n", t.Size())
+	return nil
+}
+
+func (t *AlarmTracker) restore(msg *kafka.Message) {
+	if msg.Value == nil {
+		t.Transitions(string(msg.Key), nil, msg.Timestamp.UnixNano()/1e6)
+		return
+	}
+	alarm := &producer.Alarm{}
+	if err := proto.Unmarshal(msg.Value, alarm); err != nil {
+		log.Printf("invalid alarm message received from %s: %v\n", msg.TopicPartition, err)
+		return
+	}
+	t.Transitions(string(msg.Key), alarm, 0)
+}
+
+// Size returns the number of tracked alarms.
+func (t *AlarmTracker) Size() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.alarms)
+}
+
+// Transitions updates the snapshot of a given alarm, and returns the transitions from the previous one.
+// A nil alarm means it was deleted, and the given time (in milliseconds) is used for the transition.
+func (t *AlarmTracker) Transitions(key string, alarm *producer.Alarm, deleteTime int64) []AlarmTransition {
+	t.mutex.Lock()
+	before, ok := t.alarms[key]
+	if alarm == nil {
+		delete(t.alarms, key)
+	} else {
+		t.alarms[key] = alarm
+	}
+	t.mutex.Unlock()
+
+	transitions := make([]AlarmTransition, 0)
+	if alarm == nil {
+		if ok {
+			tr := newTransition(alarmDeleted, key, before, uint64(deleteTime))
+			tr.Before = before.Severity.String()
+			transitions = append(transitions, tr)
+		}
+		return transitions
+	}
+	if !ok {
+		tr := newTransition(alarmCreated, key, alarm, alarm.FirstEventTime)
+		tr.After = alarm.Severity.String()
+		return append(transitions, tr)
+	}
+	if alarm.Severity != before.Severity {
+		kind := alarmEscalated
+		if alarm.Severity == producer.Severity_CLEARED {
+			kind = alarmCleared
+		} else if alarm.Severity < before.Severity {
+			kind = alarmDeescalated
+		}
+		tr := newTransition(kind, key, alarm, alarm.LastEventTime)
+		tr.Before, tr.After = before.Severity.String(), alarm.Severity.String()
+		transitions = append(transitions, tr)
+	}
+	if alarm.AckUser != before.AckUser || alarm.AckTime != before.AckTime {
+		if alarm.AckUser != "" && before.AckUser == "" {
+			tr := newTransition(alarmAcknowledged, key, alarm, alarm.AckTime)
+			tr.After = alarm.AckUser
+			transitions = append(transitions, tr)
+		} else if alarm.AckUser == "" && before.AckUser != "" {
+			tr := newTransition(alarmUnacknowledged, key, alarm, alarm.LastEventTime)
+			tr.Before = before.AckUser
+			transitions = append(transitions, tr)
+		}
+	}
+	if alarm.Count > before.Count {
+		tr := newTransition(alarmCountIncreased, key, alarm, alarm.LastEventTime)
+		tr.Before, tr.After = before.Count, alarm.Count
+		transitions = append(transitions, tr)
+	}
+	return transitions
+}
+
+func newTransition(kind string, key string, alarm *producer.Alarm, time uint64) AlarmTransition {
+	return AlarmTransition{
+		Type:         kind,
+		Key:          key,
+		AlarmID:      alarm.Id,
+		ReductionKey: alarm.ReductionKey,
+		UEI:          alarm.Uei,
+		Time:         time,
+	}
+}
+
+// processLifecycle emits the transitions of a given alarm; a nil alarm means it was deleted.
+func (cli *KafkaClient) processLifecycle(msg *kafka.Message, alarm *producer.Alarm) {
+	transitions := cli.lifecycle.Transitions(string(msg.Key), alarm, msg.Timestamp.UnixNano()/1e6)
+	for _, tr := range transitions {
+		value, err := json.Marshal(&tr)
+		if err == nil && cli.JSONTimestamps {
+			value, err = convertTimestamps(value)
+		}
+		if err != nil {
+			log.Printf("cannot convert %s transition for alarm %d: %v\n", tr.Type, tr.AlarmID, err)
+			continue
+		}
+		cli.produce(msg, cli.LifecycleTopic, msg.Key, value)
+		if cli.Debug {
+			log.Printf("alarm transition: %s\n", string(value))
+		}
+	}
+}
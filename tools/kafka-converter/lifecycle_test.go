@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/proto"
+	"gotest.tools/assert"
+)
+
+func TestAlarmTransitions(t *testing.T) {
+	tracker := NewAlarmTracker(nil)
+	key := "uei.opennms.org/nodes/nodeDown::1"
+	alarm := &producer.Alarm{Id: 1, ReductionKey: key, Severity: producer.Severity_MINOR, Count: 1, FirstEventTime: 1000, LastEventTime: 1000}
+	transitions := tracker.Transitions(key, alarm, 0)
+	assert.Equal(t, 1, len(transitions))
+	assert.Equal(t, alarmCreated, transitions[0].Type)
+	assert.Equal(t, "MINOR", transitions[0].After)
+
+	// No changes
+	assert.Equal(t, 0, len(tracker.Transitions(key, alarm, 0)))
+
+	alarm = &producer.Alarm{Id: 1, ReductionKey: key, Severity: producer.Severity_MAJOR, Count: 2, LastEventTime: 2000}
+	transitions = tracker.Transitions(key, alarm, 0)
+	assert.Equal(t, 2, len(transitions))
+	assert.Equal(t, alarmEscalated, transitions[0].Type)
+	assert.Equal(t, "MINOR", transitions[0].Before)
+	assert.Equal(t, "MAJOR", transitions[0].After)
+	assert.Equal(t, alarmCountIncreased, transitions[1].Type)
+	assert.Equal(t, uint64(1), transitions[1].Before)
+	assert.Equal(t, uint64(2), transitions[1].After)
+
+	alarm = &producer.Alarm{Id: 1, ReductionKey: key, Severity: producer.Severity_MAJOR, Count: 2, LastEventTime: 2000, AckUser: "admin", AckTime: 3000}
+	transitions = tracker.Transitions(key, alarm, 0)
+	assert.Equal(t, 1, len(transitions))
+	assert.Equal(t, alarmAcknowledged, transitions[0].Type)
+	assert.Equal(t, uint64(3000), transitions[0].Time)
+
+	alarm = &producer.Alarm{Id: 1, ReductionKey: key, Severity: producer.Severity_CLEARED, Count: 2, LastEventTime: 4000}
+	transitions = tracker.Transitions(key, alarm, 0)
+	assert.Equal(t, 2, len(transitions))
+	assert.Equal(t, alarmCleared, transitions[0].Type)
+	assert.Equal(t, alarmUnacknowledged, transitions[1].Type)
+	assert.Equal(t, "admin", transitions[1].Before)
+
+	transitions = tracker.Transitions(key, nil, 5000)
+	assert.Equal(t, 1, len(transitions))
+	assert.Equal(t, alarmDeleted, transitions[0].Type)
+	assert.Equal(t, uint64(5000), transitions[0].Time)
+	assert.Equal(t, 0, tracker.Size())
+
+	// Unknown alarms are ignored when deleted
+	assert.Equal(t, 0, len(tracker.Transitions(key, nil, 6000)))
+}
+
+func TestLifecycleRoutes(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewFileSink(dir, 0, 0)
+	assert.NilError(t, err)
+	cli := &KafkaClient{
+		LifecycleTopic: "lifecycle",
+		encoder:        &JSONEncoder{Format: legacyJSONFormat},
+		sink:           sink,
+		lifecycle:      NewAlarmTracker(nil),
+		routes: map[string]*Route{
+			"OpenNMS-alarms": {SourceTopic: "OpenNMS-alarms", MessageKind: alarmKind, DestTopics: []string{"alarms"}},
+			"OpenNMS-mixed":  {SourceTopic: "OpenNMS-mixed", MessageKind: autoKind, DestTopics: []string{"mixed"}},
+		},
+	}
+	value, err := proto.Marshal(&producer.Alarm{Id: 1, Uei: "uei.opennms.org/nodes/nodeDown", ReductionKey: "nodeDown::1", Severity: producer.Severity_MAJOR})
+	assert.NilError(t, err)
+	for _, topic := range []string{"OpenNMS-alarms", "OpenNMS-mixed"} {
+		topic := topic
+		cli.processMessage(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic}, Key: []byte(topic), Value: value})
+	}
+	sink.Close()
+
+	// Only the alarm route is tracked, as the snapshots of auto routes are not restored on startup
+	content := readSinkFiles(t, dir)
+	assert.Assert(t, content["alarms"] != "")
+	assert.Assert(t, content["mixed"] != "")
+	assert.Equal(t, 1, strings.Count(content["lifecycle"], "\n"))
+	assert.Assert(t, strings.Contains(content["lifecycle"], `"key":"OpenNMS-alarms"`))
+	assert.Equal(t, 1, cli.lifecycle.Size())
+}
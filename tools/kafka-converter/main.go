@@ -2,14 +2,19 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/agalue/kafka-converter/api/flows"
 	"github.com/agalue/kafka-converter/api/producer"
 	"github.com/confluentinc/confluent-kafka-go/kafka"
 	"github.com/golang/protobuf/proto"
@@ -17,14 +22,50 @@ import (
 )
 
 const (
-	eventKind  = "event"
-	alarmKind  = "alarm"
-	nodeKind   = "node"
-	edgeKind   = "edge"
-	metricKind = "metric"
+	eventKind    = "event"
+	alarmKind    = "alarm"
+	nodeKind     = "node"
+	edgeKind     = "edge"
+	metricKind   = "metric"
+	feedbackKind = "feedback"
+	flowKind     = "flow"
 )
 
-var kinds = []string{eventKind, alarmKind, nodeKind, edgeKind, metricKind}
+var kinds = []string{eventKind, alarmKind, nodeKind, edgeKind, metricKind, feedbackKind, flowKind}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func isValidKind(kind string) bool {
+	return contains(kinds, kind) || isDynamicKind(kind)
+}
+
+// newMessage returns an empty GPB message for the given kind, which can be a type loaded at runtime.
+func newMessage(kind string) proto.Message {
+	switch kind {
+	case eventKind:
+		return &producer.Event{}
+	case alarmKind:
+		return &producer.Alarm{}
+	case nodeKind:
+		return &producer.Node{}
+	case edgeKind:
+		return &producer.TopologyEdge{}
+	case metricKind:
+		return &producer.CollectionSet{}
+	case feedbackKind:
+		return &producer.AlarmFeedback{}
+	case flowKind:
+		return &flows.FlowDocument{}
+	}
+	return newDynamicMessage(kind)
+}
 
 // KafkaClient represents a Kafka consumer/producer client application.
 type KafkaClient struct {
@@ -33,12 +74,92 @@ type KafkaClient struct {
 	DestTopic        string
 	FlatDestTopic    string
 	MessageKind      string
+	Routes           string
+	RoutesFile       string
+	DescriptorFiles  string
+	InputFiles       string
+	InputFormat      string
+	OutputDir        string
+	OutputRotateSize int
+	OutputRotateTime time.Duration
+	ParquetDir       string
+	ParquetKinds     string
+	ParquetMaxSize   int
+	ParquetInterval  time.Duration
+	Filter           string
+	Transform        string
 	GroupID          string
 	ProducerSettings string
 	ConsumerSettings string
+	JSONFormat       string
+	JSONEnumStrings  bool
+	JSONTimestamps   bool
+	CloudEvents      string
+	EnrichNodesTopic string
+	EnrichDestTopic  string
+	EnrichMissing    string
+	EnrichDefer      time.Duration
+	DeadLetterTopic  string
+	Guarantee        string
+	DeliveryRetries  int
+	TransactionalID  string
+	TransactionBatch int
+	TransactionTime  time.Duration
+	HTTPListen       string
+	PrometheusTTL    time.Duration
+	AlarmTableTopic  string
+	TopologyTopic    string
+	RemoteWriteURL   string
+	RemoteWriteBatch int
+	RemoteWriteFlush time.Duration
+	RemoteWriteRetry int
+	InfluxTopic      string
+	InfluxURL        string
+	InfluxToken      string
+	InfluxStrings    string
+	InfluxBatch      int
+	InfluxFlush      time.Duration
+	Tombstones       string
+	LifecycleTopic   string
+	ExplodeTopic     string
+	ExplodeMode      string
+	CounterRates     bool
+	RateStateTopic   string
+	RateSnapshot     string
+	RateInterval     time.Duration
+	Rollups          string
+	RollupLateness   time.Duration
+	FeedbackTopic    string
+	FeedbackWindow   time.Duration
 	Debug            bool
 	producer         *kafka.Producer
 	consumer         *kafka.Consumer
+	sink             *FileSink
+	parquet          *ParquetSink
+	inputFiles       []string
+	routes           map[string]*Route
+	encoder          *JSONEncoder
+	server           *http.Server
+	metrics          *PrometheusExporter
+	alarmTable       *AlarmTable
+	topology         *Topology
+	remoteWriter     *RemoteWriter
+	influx           *InfluxConverter
+	influxWriter     *InfluxWriter
+	rates            *RateConverter
+	rollups          []*Rollup
+	feedback         *FeedbackReport
+	sinkAssembler    *SinkAssembler
+	enricher         *Enricher
+	lifecycle        *AlarmTracker
+	tracker          *OffsetTracker
+	stopping         chan struct{}
+	done             chan struct{}
+	failed           chan error
+	wg               sync.WaitGroup
+	retryMutex       sync.Mutex
+	retriesStopped   bool
+	pendingRetries   int
 }
 
 func (cli *KafkaClient) getKafkaConfig(properties string) *kafka.ConfigMap {
@@ -59,25 +180,350 @@ func (cli *KafkaClient) getKafkaConfig(properties string) *kafka.ConfigMap {
 }
 
 func (cli *KafkaClient) validate() error {
-	if cli.SourceTopic == "" {
-		return fmt.Errorf("source topic cannot be empty")
+	var list []Route
+	var err error
+	if cli.DescriptorFiles != "" {
+		if err = loadDescriptors(cli.DescriptorFiles); err != nil {
+			return err
+		}
+	}
+	if cli.RoutesFile != "" {
+		if list, err = loadRoutes(cli.RoutesFile); err != nil {
+			return err
+		}
+	}
+	routes, err := parseRoutes(cli.Routes)
+	if err != nil {
+		return err
+	}
+	list = append(list, routes...)
+	if len(list) == 0 {
+		// Single route from the legacy flags
+		route := Route{
+			SourceTopic:   cli.SourceTopic,
+			MessageKind:   cli.MessageKind,
+			FlatDestTopic: cli.FlatDestTopic,
+			Filter:        cli.Filter,
+		}
+		if cli.DestTopic != "" {
+			route.DestTopics = []string{cli.DestTopic}
+			if cli.Transform != "" {
+				route.Transforms = map[string]string{cli.DestTopic: cli.Transform}
+			}
+		}
+		list = append(list, route)
+	}
+	if (cli.InfluxTopic != "" || cli.InfluxURL != "") && cli.InfluxStrings != influxStringAsTag && cli.InfluxStrings != influxStringAsField {
+		return fmt.Errorf("invalid InfluxDB string attributes handling %s. Valid options: %s, %s", cli.InfluxStrings, influxStringAsTag, influxStringAsField)
+	}
+	if cli.InfluxURL != "" && cli.InfluxFlush <= 0 {
+		return fmt.Errorf("invalid InfluxDB flush interval %s; expected a positive duration", cli.InfluxFlush)
+	}
+	if cli.ExplodeTopic != "" && !contains(explodeModes, cli.ExplodeMode) {
+		return fmt.Errorf("invalid explode mode %s. Valid options: %s", cli.ExplodeMode, strings.Join(explodeModes, ", "))
+	}
+	if cli.rollups, err = parseRollups(cli.Rollups, cli.RollupLateness); err != nil {
+		return err
+	}
+	for _, rollup := range cli.rollups {
+		rollup.Hold = cli.holdDelivery // Keep the offsets of the samples uncommitted until their windows are emitted
+	}
+	if cli.FeedbackTopic != "" {
+		if cli.FeedbackWindow < time.Second {
+			return fmt.Errorf("invalid feedback report window %s; expected a duration of at least 1s", cli.FeedbackWindow)
+		}
+		cli.feedback = NewFeedbackReport(cli.FeedbackWindow, cli.FeedbackTopic, cli.RollupLateness)
+		cli.feedback.Hold = cli.holdDelivery
+	}
+	if cli.Tombstones == "" {
+		cli.Tombstones = tombstoneForward
+	}
+	if !contains(tombstoneModes, cli.Tombstones) {
+		return fmt.Errorf("invalid tombstone handling %s. Valid options: %s", cli.Tombstones, strings.Join(tombstoneModes, ", "))
+	}
+	if cli.AlarmTableTopic != "" && cli.HTTPListen == "" {
+		return fmt.Errorf("the HTTP server is required for the alarm table")
+	}
+	if cli.TopologyTopic != "" && cli.HTTPListen == "" {
+		return fmt.Errorf("the HTTP server is required for the topology")
+	}
+	if cli.JSONFormat == "" {
+		cli.JSONFormat = legacyJSONFormat
+	}
+	cli.encoder = &JSONEncoder{
+		Format:         cli.JSONFormat,
+		EnumsAsStrings: cli.JSONEnumStrings,
+		TimesAsRFC3339: cli.JSONTimestamps,
 	}
-	if cli.DestTopic == "" {
-		return fmt.Errorf("destination topic cannot be empty")
+	if err := cli.encoder.validate(); err != nil {
+		return fmt.Errorf("%v. Valid options: %s", err, strings.Join(jsonFormats, ", "))
 	}
-	if cli.MessageKind == "" {
-		return fmt.Errorf("message kind cannot be empty")
+	if cli.CloudEvents != "" && cli.CloudEvents != structuredMode && cli.CloudEvents != binaryMode {
+		return fmt.Errorf("invalid CloudEvents mode %s. Valid options: %s, %s", cli.CloudEvents, structuredMode, binaryMode)
 	}
-	set := make(map[string]bool, len(kinds))
-	for _, s := range kinds {
-		set[s] = true
+	if cli.EnrichDestTopic != "" {
+		if cli.EnrichNodesTopic == "" {
+			return fmt.Errorf("nodes topic cannot be empty when enriching alarms")
+		}
+		if err := validateMissingNodePolicy(cli.EnrichMissing); err != nil {
+			return err
+		}
+		if cli.EnrichMissing == missingNodeDefer && cli.Guarantee == exactlyOnceGuarantee {
+			return fmt.Errorf("the %s missing node policy cannot be used with the %s delivery guarantee", missingNodeDefer, exactlyOnceGuarantee)
+		}
+	}
+	if cli.Guarantee == "" {
+		cli.Guarantee = autoCommitGuarantee
 	}
-	if _, ok := set[cli.MessageKind]; !ok {
-		return fmt.Errorf("invalid message kind %s. Valid options: %s", cli.MessageKind, strings.Join(kinds, ", "))
+	if !contains(deliveryGuarantees, cli.Guarantee) {
+		return fmt.Errorf("invalid delivery guarantee %s. Valid options: %s", cli.Guarantee, strings.Join(deliveryGuarantees, ", "))
+	}
+	if cli.Guarantee == exactlyOnceGuarantee && (len(cli.rollups) > 0 || cli.feedback != nil) {
+		// Their windows span several transactions, so the offsets of their samples would be committed before emitting them
+		return fmt.Errorf("the rollups and the feedback report cannot be used with the %s delivery guarantee", exactlyOnceGuarantee)
+	}
+	if (cli.InputFiles != "" || cli.OutputDir != "") && cli.Guarantee != autoCommitGuarantee {
+		return fmt.Errorf("the %s delivery guarantee requires Kafka as source and destination", cli.Guarantee)
+	}
+	if cli.InputFiles != "" {
+		if !contains(inputFormats, cli.InputFormat) {
+			return fmt.Errorf("invalid input format %s. Valid options: %s", cli.InputFormat, strings.Join(inputFormats, ", "))
+		}
+		if cli.InputFormat != captureInput && len(list) > 1 {
+			return fmt.Errorf("the %s input format requires a single route", cli.InputFormat)
+		}
+		if cli.inputFiles, err = expandInputFiles(cli.InputFiles); err != nil {
+			return err
+		}
+	}
+	if cli.ParquetDir != "" {
+		if cli.InputFiles == "" && cli.Guarantee != atLeastOnceGuarantee {
+			return fmt.Errorf("the parquet output requires the %s delivery guarantee", atLeastOnceGuarantee)
+		}
+		for _, kind := range strings.Split(cli.ParquetKinds, ",") {
+			if !contains(parquetKinds, strings.TrimSpace(kind)) {
+				return fmt.Errorf("invalid parquet kind %s. Valid options: %s", kind, strings.Join(parquetKinds, ", "))
+			}
+		}
+	}
+	cli.sinkAssembler = NewSinkAssembler(sinkChunkTimeout)
+	cli.sinkAssembler.Hold = cli.holdDelivery
+	cli.sinkAssembler.Release = cli.releaseDelivery
+	cli.routes = make(map[string]*Route, len(list))
+	for i := range list {
+		route := &list[i]
+		if err := route.validate(); err != nil {
+			return err
+		}
+		if isSinkKind(route.MessageKind) && cli.Guarantee == exactlyOnceGuarantee {
+			// The chunks of a message might span several transactions
+			return fmt.Errorf("the %s message kind on %s cannot be used with the %s delivery guarantee", route.MessageKind, route.SourceTopic, exactlyOnceGuarantee)
+		}
+		if _, ok := cli.routes[route.SourceTopic]; ok {
+			return fmt.Errorf("source topic %s cannot be used on more than one route", route.SourceTopic)
+		}
+		cli.routes[route.SourceTopic] = route
 	}
 	return nil
 }
 
+func (cli *KafkaClient) sourceTopics() []string {
+	topics := make([]string, 0, len(cli.routes))
+	for topic := range cli.routes {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// produce sends a record derived from a given source message.
+func (cli *KafkaClient) produce(src *kafka.Message, topic string, key []byte, value []byte, headers ...kafka.Header) {
+	m := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Value:          value,
+		Key:            key,
+		Headers:        headers,
+	}
+	if d, ok := src.Opaque.(*delivery); ok && cli.tracker.Add(d) {
+		m.Opaque = &outbound{delivery: d}
+	}
+	cli.send(m)
+}
+
+func (cli *KafkaClient) processMessage(msg *kafka.Message) {
+	route, ok := cli.routes[*msg.TopicPartition.Topic]
+	if !ok {
+		log.Printf("no route found for topic %s\n", *msg.TopicPartition.Topic)
+		return
+	}
+	if msg.Value == nil {
+		cli.processTombstone(msg, route)
+		return
+	}
+	if isSinkKind(route.MessageKind) {
+		cli.processSink(msg, route)
+		return
+	}
+	kind := route.MessageKind
+	var data proto.Message
+	if kind == autoKind {
+		var err error
+		if kind, data, err = detectKind(route.SourceTopic, msg.Value); err != nil {
+			cli.deadLetter(msg, autoKind, err)
+			return
+		}
+	} else {
+		data = newMessage(kind)
+		if err := proto.Unmarshal(msg.Value, data); err != nil {
+			cli.deadLetter(msg, kind, fmt.Errorf("invalid %s message: %v", kind, err))
+			return
+		}
+	}
+	if cs, ok := data.(*producer.CollectionSet); ok {
+		if cli.rates != nil {
+			cli.processRates(msg, cs)
+		}
+		if cli.metrics != nil {
+			cli.metrics.Update(cs)
+		}
+		if cli.remoteWriter != nil {
+			cli.remoteWriter.Add(cs)
+		}
+		if cli.influx != nil {
+			cli.processInflux(msg, cs)
+		}
+		if cli.ExplodeTopic != "" {
+			cli.processExplode(msg, cs)
+		}
+		if len(cli.rollups) > 0 {
+			cli.processRollups(msg, cs)
+		}
+	}
+	if cli.parquet != nil {
+		cli.processParquet(msg, kind, data)
+	}
+	jsonBytes, err := cli.encoder.Marshal(data)
+	if err != nil {
+		cli.deadLetter(msg, kind, fmt.Errorf("cannot convert GPB to JSON: %v", err))
+		return
+	}
+	if alarm, ok := data.(*producer.Alarm); ok {
+		if cli.enricher != nil {
+			cli.enricher.Process(msg, alarm)
+		}
+		// The snapshots are only restored from the alarm routes, so auto routes are not tracked
+		if cli.lifecycle != nil && route.MessageKind == alarmKind {
+			cli.processLifecycle(msg, alarm)
+		}
+	}
+	if feedback, ok := data.(*producer.AlarmFeedback); ok && cli.feedback != nil {
+		cli.processFeedback(msg, feedback)
+	}
+	cli.forward(msg, route, kind, data, jsonBytes)
+}
+
+// forward sends a converted message to the destination topics of a given route.
+func (cli *KafkaClient) forward(msg *kafka.Message, route *Route, kind string, data proto.Message, jsonBytes []byte) {
+	for _, topic := range route.DestTopics {
+		if !route.accepts(topic, kind, data) {
+			continue
+		}
+		reshaped, err := route.reshape(topic, jsonBytes)
+		if err != nil {
+			cli.deadLetter(msg, kind, fmt.Errorf("cannot transform JSON for %s: %v", topic, err))
+			continue
+		}
+		value, headers, err := cli.wrap(msg, kind, data, reshaped)
+		if err != nil {
+			cli.deadLetter(msg, kind, fmt.Errorf("cannot build CloudEvent: %v", err))
+			return
+		}
+		cli.produce(msg, topic, msg.Key, value, headers...)
+		if cli.Debug {
+			log.Printf("JSON message for %s: %s\n", topic, string(value))
+		}
+	}
+	if route.FlatDestTopic != "" && route.accepts(route.FlatDestTopic, kind, data) {
+		reshaped, err := route.reshape(route.FlatDestTopic, jsonBytes)
+		if err != nil {
+			cli.deadLetter(msg, kind, fmt.Errorf("cannot transform JSON for %s: %v", route.FlatDestTopic, err))
+			return
+		}
+		flat, err := flatten.FlattenString(string(reshaped), "", flatten.UnderscoreStyle)
+		if err == nil {
+			cli.produce(msg, route.FlatDestTopic, msg.Key, []byte(flat))
+			if cli.Debug {
+				log.Printf("JSON flat message: %s\n", flat)
+			}
+		} else {
+			cli.deadLetter(msg, kind, fmt.Errorf("cannot flat JSON: %v", err))
+		}
+	}
+}
+
+// wrap returns the payload and headers for a converted message, based on the CloudEvents settings.
+func (cli *KafkaClient) wrap(msg *kafka.Message, kind string, data proto.Message, jsonBytes []byte) ([]byte, []kafka.Header, error) {
+	if cli.CloudEvents == "" {
+		return jsonBytes, nil, nil
+	}
+	event := newCloudEvent(msg, kind, data, jsonBytes)
+	if cli.CloudEvents == binaryMode {
+		return jsonBytes, event.Headers(), nil
+	}
+	value, err := json.Marshal(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, []kafka.Header{{Key: "content-type", Value: []byte(cloudEventsContentType)}}, nil
+}
+
+// emitEnhancedAlarm sends an alarm combined with its node to the enrichment destination topic.
+func (cli *KafkaClient) emitEnhancedAlarm(msg *kafka.Message, alarm *producer.Alarm, node *producer.Node) {
+	var err error
+	enhanced := EnhancedAlarm{}
+	if enhanced.Alarm, err = cli.encoder.Marshal(alarm); err != nil {
+		log.Printf("cannot convert alarm to JSON: %v\n", err)
+		return
+	}
+	if node != nil {
+		if enhanced.Node, err = cli.encoder.Marshal(node); err != nil {
+			log.Printf("cannot convert node to JSON: %v\n", err)
+			return
+		}
+	}
+	jsonBytes, err := json.Marshal(enhanced)
+	if err != nil {
+		log.Printf("cannot build enhanced alarm: %v\n", err)
+		return
+	}
+	value, headers, err := cli.wrap(msg, enhancedAlarmKind, alarm, jsonBytes)
+	if err != nil {
+		log.Printf("cannot build CloudEvent: %v\n", err)
+		return
+	}
+	cli.produce(msg, cli.EnrichDestTopic, msg.Key, value, headers...)
+	if cli.Debug {
+		log.Printf("enhanced alarm: %s\n", string(value))
+	}
+}
+
+func (cli *KafkaClient) processInflux(msg *kafka.Message, cs *producer.CollectionSet) {
+	lines := cli.influx.Convert(cs)
+	if len(lines) == 0 {
+		return
+	}
+	if cli.InfluxTopic != "" {
+		for _, line := range lines {
+			cli.produce(msg, cli.InfluxTopic, msg.Key, []byte(line))
+		}
+	}
+	if cli.influxWriter != nil {
+		cli.influxWriter.Add(lines)
+	}
+	if cli.Debug {
+		log.Printf("InfluxDB lines: %s\n", strings.Join(lines, "\n"))
+	}
+}
+
 func (cli *KafkaClient) start() error {
 	var err error
 	jsonBytes, _ := json.MarshalIndent(cli, "", "  ")
@@ -86,118 +532,403 @@ func (cli *KafkaClient) start() error {
 	if err = cli.validate(); err != nil {
 		return err
 	}
+	cli.failed = make(chan error, 1)
 
-	// Build producer
-	cli.producer, err = kafka.NewProducer(cli.getKafkaConfig(cli.ProducerSettings))
-	if err != nil {
-		return fmt.Errorf("could not create producer: %v", err)
+	// Build HTTP server
+	if cli.HTTPListen != "" {
+		cli.metrics = NewPrometheusExporter(cli.PrometheusTTL)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", cli.metrics)
+		if cli.AlarmTableTopic != "" {
+			cli.alarmTable = NewAlarmTable(cli.AlarmTableTopic, cli.encoder)
+			config := cli.getKafkaConfig(cli.ConsumerSettings)
+			config.SetKey("group.id", cli.GroupID+"-alarms")
+			if err := cli.alarmTable.Start(config); err != nil {
+				return fmt.Errorf("could not initialize alarm table: %v", err)
+			}
+			mux.Handle("/alarms", cli.alarmTable)
+			mux.Handle("/alarms/", cli.alarmTable)
+		}
+		if cli.TopologyTopic != "" {
+			cli.topology = NewTopology(cli.TopologyTopic)
+			config := cli.getKafkaConfig(cli.ConsumerSettings)
+			config.SetKey("group.id", cli.GroupID+"-topology")
+			if err := cli.topology.Start(config, false); err != nil {
+				return fmt.Errorf("could not initialize topology: %v", err)
+			}
+			mux.Handle("/topology", cli.topology)
+		}
+		cli.server = &http.Server{Addr: cli.HTTPListen, Handler: mux}
+		go func() {
+			if err := cli.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("HTTP server error: %v\n", err)
+			}
+		}()
+		log.Printf("HTTP server listening on %s\n", cli.HTTPListen)
+	}
+
+	// Build Prometheus remote writer
+	if cli.RemoteWriteURL != "" {
+		cli.remoteWriter = NewRemoteWriter(cli.RemoteWriteURL, cli.RemoteWriteBatch, cli.RemoteWriteFlush, cli.RemoteWriteRetry)
+		cli.remoteWriter.Start()
+	}
+
+	// Build InfluxDB converter
+	if cli.InfluxTopic != "" || cli.InfluxURL != "" {
+		cli.influx = &InfluxConverter{StringsAsTags: cli.InfluxStrings == influxStringAsTag}
+		if cli.InfluxURL != "" {
+			cli.influxWriter = NewInfluxWriter(cli.InfluxURL, cli.InfluxToken, cli.InfluxBatch, cli.InfluxFlush)
+			cli.influxWriter.Start()
+		}
+	}
+
+	// Build counter-to-rate converter
+	if cli.CounterRates {
+		cli.rates = NewRateConverter()
+		cli.rates.StateTopic = cli.RateStateTopic
+		cli.rates.SnapshotFile = cli.RateSnapshot
+		cli.rates.SnapshotInterval = cli.RateInterval
+		config := cli.getKafkaConfig(cli.ConsumerSettings)
+		config.SetKey("group.id", cli.GroupID+"-rates")
+		if err := cli.rates.Start(config); err != nil {
+			return fmt.Errorf("could not initialize counter state: %v", err)
+		}
+	}
+
+	// Build producer, or the file sink
+	if cli.OutputDir != "" {
+		if cli.sink, err = NewFileSink(cli.OutputDir, int64(cli.OutputRotateSize)*1024*1024, cli.OutputRotateTime); err != nil {
+			return err
+		}
+		cli.sink.Start()
+	} else if err = cli.startProducer(); err != nil {
+		return err
+	}
+
+	// Discard the incomplete Sink messages periodically
+	for _, route := range cli.routes {
+		if isSinkKind(route.MessageKind) {
+			cli.sinkAssembler.Start()
+			break
+		}
+	}
+
+	// Build node table for alarm enrichment
+	if cli.EnrichDestTopic != "" {
+		cli.enricher = &Enricher{
+			NodesTopic:   cli.EnrichNodesTopic,
+			MissingNode:  cli.EnrichMissing,
+			DeferTimeout: cli.EnrichDefer,
+			Emit:         cli.emitEnhancedAlarm,
+			Hold:         cli.holdDelivery, // Keep the offsets of the deferred alarms uncommitted until they are emitted
+			Release:      cli.releaseDelivery,
+		}
+		config := cli.getKafkaConfig(cli.ConsumerSettings)
+		config.SetKey("group.id", cli.GroupID+"-nodes")
+		if err := cli.enricher.Start(config); err != nil {
+			return fmt.Errorf("could not initialize node table: %v", err)
+		}
+	}
+
+	// Build Parquet sink, releasing the deliveries once the files are closed
+	if cli.ParquetDir != "" {
+		kinds := make([]string, 0)
+		for _, kind := range strings.Split(cli.ParquetKinds, ",") {
+			kinds = append(kinds, strings.TrimSpace(kind))
+		}
+		if cli.parquet, err = NewParquetSink(cli.ParquetDir, kinds, int64(cli.ParquetMaxSize)*1024*1024, cli.ParquetInterval); err != nil {
+			return err
+		}
+		cli.parquet.Release = func(deliveries []*delivery) {
+			for _, d := range deliveries {
+				cli.tracker.Done(d)
+			}
+		}
+		cli.parquet.Fail = cli.fail
+		cli.parquet.Start()
+	}
+
+	// Process the input files, without tracking alarms from previous runs
+	cli.stopping = make(chan struct{})
+	cli.done = make(chan struct{})
+	if cli.InputFiles != "" {
+		if cli.LifecycleTopic != "" {
+			cli.lifecycle = NewAlarmTracker(nil)
+		}
+		cli.wg.Add(1)
+		go func() {
+			defer cli.wg.Done()
+			cli.fileLoop()
+		}()
+		log.Printf("processing %d input files\n", len(cli.inputFiles))
+		return nil
 	}
 
 	// Build consumer
 	config := cli.getKafkaConfig(cli.ConsumerSettings)
 	config.SetKey("group.id", cli.GroupID)
+	switch cli.Guarantee {
+	case atLeastOnceGuarantee:
+		config.SetKey("enable.auto.offset.store", false)
+	case exactlyOnceGuarantee:
+		config.SetKey("enable.auto.commit", false)
+	}
 	cli.consumer, err = kafka.NewConsumer(config)
 	if err != nil {
 		return fmt.Errorf("could not create consumer: %v", err)
 	}
-	cli.consumer.SubscribeTopics([]string{cli.SourceTopic}, nil)
+
+	// Build alarm tracker for lifecycle transitions, up to the committed offsets
+	if cli.LifecycleTopic != "" {
+		topics := make([]string, 0)
+		for _, route := range cli.routes {
+			if route.MessageKind == alarmKind {
+				topics = append(topics, route.SourceTopic)
+			}
+		}
+		cli.lifecycle = NewAlarmTracker(topics)
+		config := cli.getKafkaConfig(cli.ConsumerSettings)
+		config.SetKey("group.id", cli.GroupID+"-lifecycle")
+		committed := func(partitions []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+			return cli.consumer.Committed(partitions, 10000)
+		}
+		if err := cli.lifecycle.Start(config, committed); err != nil {
+			return fmt.Errorf("could not initialize alarm tracker: %v", err)
+		}
+	}
+	cli.consumer.SubscribeTopics(cli.sourceTopics(), nil)
+
+	// Start consumer Loop
+	if cli.Guarantee == atLeastOnceGuarantee {
+		cli.tracker = NewOffsetTracker(cli.consumer.StoreOffsets)
+	}
+	cli.wg.Add(1)
+	go func() {
+		defer cli.wg.Done()
+		if cli.Guarantee == exactlyOnceGuarantee {
+			cli.transactionalLoop()
+		} else {
+			cli.consumerLoop()
+		}
+	}()
+
+	log.Printf("kafka consumer started against %s\n", cli.Bootstrap)
+	return nil
+}
+
+func (cli *KafkaClient) startProducer() error {
+	var err error
+	config := cli.getKafkaConfig(cli.ProducerSettings)
+	switch cli.Guarantee {
+	case atLeastOnceGuarantee:
+		config.SetKey("acks", "all")
+	case exactlyOnceGuarantee:
+		if cli.TransactionalID == "" {
+			cli.TransactionalID = cli.GroupID + "-txn"
+		}
+		config.SetKey("transactional.id", cli.TransactionalID)
+	}
+	cli.producer, err = kafka.NewProducer(config)
+	if err != nil {
+		return fmt.Errorf("could not create producer: %v", err)
+	}
+	if cli.Guarantee == exactlyOnceGuarantee {
+		if err := cli.producer.InitTransactions(context.Background()); err != nil {
+			return fmt.Errorf("could not initialize transactions: %v", err)
+		}
+	}
 
 	// Start producer messages handler
 	go func() {
 		for e := range cli.producer.Events() {
 			switch ev := e.(type) {
 			case *kafka.Message:
-				if ev.TopicPartition.Error != nil {
-					log.Printf("message delivery failed: %v\n", ev.TopicPartition.Error)
+				cli.handleDeliveryReport(ev)
+			case kafka.Error:
+				if ev.IsFatal() {
+					cli.fail(fmt.Errorf("fatal kafka producer error: %v", ev))
 				} else {
-					log.Printf("message delivered to %v\n", ev.TopicPartition)
+					log.Printf("kafka producer error: %v\n", ev)
 				}
 			default:
 				log.Printf("kafka producer event: %s\n", ev)
 			}
 		}
 	}()
+	log.Printf("kafka producer started against %s\n", cli.Bootstrap)
+	return nil
+}
 
-	// Start consumer Loop
-	go func() {
-		for {
-			msg, err := cli.consumer.ReadMessage(-1)
-			if err == nil {
-				var data proto.Message
-				switch cli.MessageKind {
-				case eventKind:
-					data = &producer.Event{}
-				case alarmKind:
-					data = &producer.Alarm{}
-				case nodeKind:
-					data = &producer.Node{}
-				case edgeKind:
-					data = &producer.TopologyEdge{}
-				case metricKind:
-					data = &producer.CollectionSet{}
-				}
-				if err := proto.Unmarshal(msg.Value, data); err != nil {
-					log.Printf("invalid %s message received: %v\n", cli.MessageKind, err)
-				}
-				jsonBytes, err := json.Marshal(data)
-				if err == nil {
-					cli.producer.Produce(&kafka.Message{
-						TopicPartition: kafka.TopicPartition{Topic: &cli.DestTopic, Partition: kafka.PartitionAny},
-						Value:          jsonBytes,
-						Key:            msg.Key,
-					}, nil)
-					if cli.Debug {
-						log.Printf("JSON message: %s\n", string(jsonBytes))
-					}
-					if cli.FlatDestTopic != "" {
-						flat, err := flatten.FlattenString(string(jsonBytes), "", flatten.UnderscoreStyle)
-						if err == nil {
-							cli.producer.Produce(&kafka.Message{
-								TopicPartition: kafka.TopicPartition{Topic: &cli.FlatDestTopic, Partition: kafka.PartitionAny},
-								Value:          []byte(flat),
-								Key:            msg.Key,
-							}, nil)
-							if cli.Debug {
-								log.Printf("JSON flat message: %s\n", string(jsonBytes))
-							}
-						} else {
-							log.Printf("cannot flat JSON: %v\n", err)
-						}
-					}
-				} else {
-					fmt.Printf("cannot convert GPB to JSON: %v\n", err)
-				}
-			} else {
-				log.Printf("kafka consumer error: %v\n", err)
-			}
+// consumerLoop processes messages until the client is stopped.
+func (cli *KafkaClient) consumerLoop() {
+	for {
+		select {
+		case <-cli.stopping:
+			return
+		default:
 		}
-	}()
+		msg := cli.pollMessage(1000)
+		if msg == nil {
+			continue
+		}
+		if cli.tracker != nil {
+			d := cli.tracker.Begin(msg)
+			msg.Opaque = d
+			cli.processMessage(msg)
+			cli.tracker.Seal(d)
+		} else {
+			cli.processMessage(msg)
+		}
+	}
+}
 
-	log.Printf("kafka consumer/producer started against %s\n", cli.Bootstrap)
-	return nil
+// fail reports an unrecoverable error, which stops the application without committing the offsets of the unprocessed messages.
+func (cli *KafkaClient) fail(err error) {
+	log.Printf("%v\n", err)
+	select {
+	case cli.failed <- err:
+	default: // Already failed
+	}
 }
 
 func (cli *KafkaClient) stop() {
-	cli.consumer.Close()
-	cli.producer.Close()
+	close(cli.stopping)
+	cli.wg.Wait()
+	// The components holding deliveries emit or release them first, so their offsets are stored before closing the consumer
+	if cli.parquet != nil {
+		cli.parquet.Stop()
+	}
+	cli.sinkAssembler.Stop()
+	if cli.enricher != nil {
+		cli.enricher.Stop()
+	}
+	if cli.producer != nil {
+		cli.flushProducer()
+	}
+	if cli.tracker != nil {
+		cli.tracker.Close()
+	}
+	if cli.consumer != nil {
+		cli.consumer.Close() // Commits the stored offsets
+	}
+	if cli.rates != nil {
+		cli.rates.Stop()
+	}
+	if cli.producer != nil {
+		cli.producer.Close()
+	}
+	if cli.sink != nil {
+		cli.sink.Close()
+	}
+	if cli.remoteWriter != nil {
+		cli.remoteWriter.Stop()
+	}
+	if cli.influxWriter != nil {
+		cli.influxWriter.Stop()
+	}
+	if cli.server != nil {
+		cli.server.Close()
+	}
+	if cli.alarmTable != nil {
+		cli.alarmTable.Stop()
+	}
+	if cli.topology != nil {
+		cli.topology.Stop()
+	}
 	log.Println("good bye!")
 }
 
 // Bootstrap function
 
+// Commands available besides the default consumer/producer mode.
+var commands = map[string]func(args []string) error{
+	"replay":   replayCommand,
+	"filter":   filterCommand,
+	"topology": topologyCommand,
+	"capture":  captureCommand,
+}
+
 func main() {
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		command, ok := commands[os.Args[1]]
+		if !ok {
+			log.Fatalf("invalid command %s", os.Args[1])
+		}
+		if err := command(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	client := KafkaClient{}
 	flag.StringVar(&client.Bootstrap, "bootstrap", "localhost:9092", "kafka bootstrap server")
 	flag.StringVar(&client.SourceTopic, "source-topic", "", "kafka source topic with OpenNMS Producer GPB messages")
 	flag.StringVar(&client.DestTopic, "dest-topic", "", "kafka destination topic for JSON generated payload")
 	flag.StringVar(&client.FlatDestTopic, "dest-topic-flat", "", "when specified, the flat content goes to this topic, and the non-flat version goes to dest-topic")
+	flag.StringVar(&client.Routes, "routes", "", "optional CSV of routes with format source-topic:message-kind:dest-topic[|dest-topic...][:flat-dest-topic]; overrides source-topic, message-kind, dest-topic and dest-topic-flat")
+	flag.StringVar(&client.RoutesFile, "routes-file", "", "optional JSON file with an array of routes (source, kind, dest, flat, filter, filters and transforms); can be combined with routes")
+	flag.StringVar(&client.DescriptorFiles, "descriptor-files", "", "optional CSV of FileDescriptorSet or .proto files (compiled with protoc) with message types to use as message kinds by their full name")
+	flag.StringVar(&client.InputFiles, "input-files", "", "optional CSV of files, directories or glob patterns to read the messages from instead of Kafka; the process exits when all of them are processed")
+	flag.StringVar(&client.InputFormat, "input-format", captureInput, "format of the input files; valid options: "+captureInput+" (created by the capture command), "+delimitedInput+" (GPB messages prefixed by their size), "+singleInput+" (one GPB message per file)")
+	flag.StringVar(&client.OutputDir, "output-dir", "", "optional directory to write the records as NDJSON files per topic instead of sending them to Kafka")
+	flag.IntVar(&client.OutputRotateSize, "output-rotate-size", 100, "maximum size in MB of the output files; 0 disables size-based rotation")
+	flag.DurationVar(&client.OutputRotateTime, "output-rotate-interval", time.Hour, "maximum age of the output files; 0 disables time-based rotation")
+	flag.StringVar(&client.ParquetDir, "parquet-dir", "", "optional directory to write Parquet files partitioned by kind, date and hour; requires the at-least-once delivery guarantee")
+	flag.StringVar(&client.ParquetKinds, "parquet-kinds", strings.Join(parquetKinds, ","), "CSV of message kinds to write to Parquet; metrics are written as exploded samples")
+	flag.IntVar(&client.ParquetMaxSize, "parquet-rotate-size", 128, "maximum uncompressed size in MB of the rows of each Parquet file; 0 disables size-based rotation")
+	flag.DurationVar(&client.ParquetInterval, "parquet-rotate-interval", 15*time.Minute, "maximum age of the Parquet files; 0 disables time-based rotation")
+	flag.StringVar(&client.Filter, "filter", "", "optional filter expression for the messages sent to dest-topic and dest-topic-flat; use filter and filters on routes-file for multiple routes")
+	flag.StringVar(&client.Transform, "transform", "", "optional Go template to reshape the JSON messages sent to dest-topic, or @ followed by the path to the template file; use transforms on routes-file for multiple routes")
 	flag.StringVar(&client.GroupID, "group-id", "kafka-converter", "kafka consumer group ID")
-	flag.StringVar(&client.MessageKind, "message-kind", alarmKind, "source topic message kind; valid options: "+strings.Join(kinds, ", "))
+	flag.StringVar(&client.MessageKind, "message-kind", alarmKind, "source topic message kind; valid options: "+strings.Join(kinds, ", ")+", "+strings.Join(sinkKinds, ", ")+", a message type from descriptor-files, or "+autoKind+" to detect it for each message")
 	flag.StringVar(&client.ProducerSettings, "producer-params", "", "optional kafka producer parameters as a CSV of Key-Value pairs")
 	flag.StringVar(&client.ConsumerSettings, "consumer-params", "", "optional kafka consumer parameters as a CSV of Key-Value pairs")
+	flag.StringVar(&client.JSONFormat, "json-format", legacyJSONFormat, "JSON format; valid options: "+legacyJSONFormat+" (encoding/json), "+protoJSONFormat+" (protojson with proto field names), "+camelJSONFormat+" (protojson with lowerCamelCase names)")
+	enumStrings := flag.String("json-enum-strings", "true", "render enums as strings instead of numbers; only for the protojson formats")
+	timestamps := flag.String("json-timestamps", "false", "convert the fields with milliseconds since epoch to RFC3339 strings")
+	flag.StringVar(&client.CloudEvents, "cloudevents", "", "optional CloudEvents content mode for the messages sent to dest-topic; valid options: "+structuredMode+", "+binaryMode)
+	flag.StringVar(&client.EnrichNodesTopic, "enrich-nodes-topic", "", "kafka topic with OpenNMS Producer GPB nodes, to build the node table for alarm enrichment")
+	flag.StringVar(&client.EnrichDestTopic, "enrich-dest-topic", "", "optional kafka destination topic for alarms enriched with their nodes (EnhancedAlarm)")
+	flag.StringVar(&client.EnrichMissing, "enrich-missing-node", missingNodeEmit, "policy for alarms whose node is unknown; valid options: "+strings.Join(missingNodePolicies, ", "))
+	flag.DurationVar(&client.EnrichDefer, "enrich-defer-timeout", time.Minute, "maximum time to wait for an unknown node when using the defer policy")
+	flag.StringVar(&client.Tombstones, "tombstones", tombstoneForward, "how to handle tombstones from the source topics (i.e. deleted alarms or nodes); valid options: "+tombstoneForward+" (forward as tombstones), "+tombstoneRecord+" (emit {\"deleted\":true,\"key\":...})")
+	flag.StringVar(&client.LifecycleTopic, "lifecycle-topic", "", "optional kafka destination topic for alarm lifecycle transitions (created, escalated, cleared, acknowledged, etc.)")
+	flag.StringVar(&client.DeadLetterTopic, "dead-letter-topic", "", "optional kafka topic for messages that cannot be decoded or converted")
+	flag.StringVar(&client.Guarantee, "delivery-guarantee", autoCommitGuarantee, "delivery guarantee; valid options: "+strings.Join(deliveryGuarantees, ", "))
+	flag.IntVar(&client.DeliveryRetries, "delivery-max-retries", 10, "maximum number of retries for failed deliveries with at-least-once")
+	flag.StringVar(&client.TransactionalID, "transactional-id", "", "kafka transactional ID for exactly-once; must be unique per instance (defaults to group-id plus -txn)")
+	flag.IntVar(&client.TransactionBatch, "transaction-batch-size", 100, "maximum number of source messages per transaction with exactly-once")
+	flag.DurationVar(&client.TransactionTime, "transaction-interval", time.Second, "maximum duration of a transaction with exactly-once")
+	flag.StringVar(&client.HTTPListen, "http-listen", "", "optional address for the embedded HTTP server, exposing /metrics for Prometheus (i.e. :8080)")
+	flag.StringVar(&client.AlarmTableTopic, "alarm-table-topic", "", "optional kafka topic with OpenNMS Producer GPB alarms, to expose the active alarms through /alarms; requires http-listen")
+	flag.StringVar(&client.TopologyTopic, "topology-topic", "", "optional kafka topic with OpenNMS Producer GPB edges, to expose the topology through /topology; requires http-listen")
+	flag.DurationVar(&client.PrometheusTTL, "prometheus-ttl", 5*time.Minute, "time after which a time series not updated is removed from /metrics")
+	flag.StringVar(&client.RemoteWriteURL, "remote-write-url", "", "optional Prometheus remote_write URL to push the metric messages (i.e. http://cortex:9009/api/v1/push)")
+	flag.IntVar(&client.RemoteWriteBatch, "remote-write-batch-size", 500, "maximum number of samples per remote_write request")
+	flag.DurationVar(&client.RemoteWriteFlush, "remote-write-flush-interval", 10*time.Second, "interval to flush pending samples to the remote_write endpoint")
+	flag.IntVar(&client.RemoteWriteRetry, "remote-write-max-retries", 5, "maximum number of retries when the remote_write endpoint returns a 5xx error")
+	flag.StringVar(&client.InfluxTopic, "influx-topic", "", "optional kafka destination topic for the metric messages using the InfluxDB line protocol")
+	flag.StringVar(&client.InfluxURL, "influx-url", "", "optional InfluxDB write URL for the metric messages (i.e. http://influxdb:8086/api/v2/write?org=opennms&bucket=metrics)")
+	flag.StringVar(&client.InfluxToken, "influx-token", "", "optional InfluxDB authentication token")
+	flag.StringVar(&client.InfluxStrings, "influx-string-attributes", influxStringAsTag, "how to handle string attributes with InfluxDB; valid options: "+influxStringAsTag+", "+influxStringAsField)
+	flag.IntVar(&client.InfluxBatch, "influx-batch-size", 5000, "maximum number of lines per InfluxDB write request")
+	flag.DurationVar(&client.InfluxFlush, "influx-flush-interval", 10*time.Second, "interval to flush pending lines to InfluxDB")
+	flag.StringVar(&client.ExplodeTopic, "explode-topic", "", "optional kafka destination topic for the metric messages exploded into one JSON record per resource or per sample, keyed by the resource ID")
+	flag.StringVar(&client.ExplodeMode, "explode-mode", explodeByResource, "how to explode the metric messages; valid options: "+strings.Join(explodeModes, ", "))
+	counterRates := flag.String("counter-rates", "false", "convert the COUNTER numeric attributes of the metric messages to per-second rates")
+	flag.StringVar(&client.RateStateTopic, "counter-state-topic", "", "optional compacted kafka topic to keep the state of the counters across restarts")
+	flag.StringVar(&client.RateSnapshot, "counter-snapshot-file", "", "optional file to keep the state of the counters across restarts")
+	flag.DurationVar(&client.RateInterval, "counter-snapshot-interval", time.Minute, "interval to save the state of the counters to the snapshot file")
+	flag.StringVar(&client.Rollups, "rollups", "", "optional CSV of windowed rollups for the metric messages with format window:dest-topic (i.e. 5m:metrics-5m,1h:metrics-1h)")
+	flag.DurationVar(&client.RollupLateness, "rollup-allowed-lateness", time.Minute, "time to wait for late samples before closing a rollup or feedback report window")
+	flag.StringVar(&client.FeedbackTopic, "feedback-report-topic", "", "optional kafka destination topic for the number of alarm feedback messages of each type per situation fingerprint and window")
+	flag.DurationVar(&client.FeedbackWindow, "feedback-report-window", time.Hour, "window size of the alarm feedback report")
 	debug := flag.String("debug", "false", "enable debug, to visualize the JSON content to be sent")
 	flag.Parse()
 	client.Debug = *debug == "true"
+	client.CounterRates = *counterRates == "true"
+	client.JSONEnumStrings = *enumStrings == "true"
+	client.JSONTimestamps = *timestamps == "true"
 
 	err := client.start()
 	if err != nil {
@@ -206,6 +937,12 @@ func main() {
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt)
-	<-stop
+	select {
+	case <-stop:
+	case <-client.done: // All the input files were processed
+	case err := <-client.failed:
+		client.stop()
+		log.Fatal(err)
+	}
 	client.stop()
 }
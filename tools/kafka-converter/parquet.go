@@ -0,0 +1,426 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/proto"
+	"github.com/xitongsys/parquet-go/writer"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// The message kinds that can be written to Parquet; metrics are written as exploded samples.
+var parquetKinds = []string{alarmKind, eventKind, nodeKind, metricKind}
+
+// Maximum size of the row groups, to limit the memory used by each open file.
+const parquetRowGroupSize = 16 * 1024 * 1024
+
+// How often the open files are checked for time-based rotation.
+const parquetCheckInterval = 10 * time.Second
+
+// The schema of the exploded samples, matching the JSON representation of SampleRecord.
+const sampleParquetSchema = `{"Tag":"name=sample, repetitiontype=REQUIRED","Fields":[
+{"Tag":"name=resource_id, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+{"Tag":"name=timestamp, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=OPTIONAL"},
+{"Tag":"name=node_id, type=INT64, repetitiontype=OPTIONAL"},
+{"Tag":"name=foreign_source, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+{"Tag":"name=foreign_id, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+{"Tag":"name=node_label, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+{"Tag":"name=location, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+{"Tag":"name=resource_type, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+{"Tag":"name=instance, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+{"Tag":"name=group, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+{"Tag":"name=name, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+{"Tag":"name=type, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"},
+{"Tag":"name=value, type=DOUBLE, repetitiontype=OPTIONAL"}]}`
+
+// parquetSchemaItem represents a field of a Parquet schema, using the JSON format of parquet-go.
+type parquetSchemaItem struct {
+	Tag    string               `json:"Tag"`
+	Fields []*parquetSchemaItem `json:"Fields,omitempty"`
+}
+
+// parquetSchema returns the Parquet schema of a given message type.
+// All the fields are optional, repeated fields are lists, and timestamps in milliseconds use the TIMESTAMP_MILLIS type.
+// Recursive fields (i.e. the related alarms) cannot be represented, so they are stored as JSON strings.
+func parquetSchema(md protoreflect.MessageDescriptor) (string, error) {
+	root := &parquetSchemaItem{
+		Tag:    fmt.Sprintf("name=%s, repetitiontype=REQUIRED", strings.ToLower(string(md.Name()))),
+		Fields: parquetFields(md, map[protoreflect.FullName]bool{md.FullName(): true}),
+	}
+	data, err := json.Marshal(root)
+	return string(data), err
+}
+
+func parquetFields(md protoreflect.MessageDescriptor, ancestors map[protoreflect.FullName]bool) []*parquetSchemaItem {
+	items := make([]*parquetSchemaItem, 0)
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		name := "name=" + string(fd.Name())
+		switch {
+		case fd.IsMap():
+			value := parquetField("name=value", "REQUIRED", fd.MapValue(), ancestors)
+			if value == nil {
+				continue
+			}
+			items = append(items, &parquetSchemaItem{
+				Tag:    name + ", type=MAP, repetitiontype=OPTIONAL",
+				Fields: []*parquetSchemaItem{parquetField("name=key", "REQUIRED", fd.MapKey(), ancestors), value},
+			})
+		case fd.IsList():
+			if element := parquetField("name=element", "REQUIRED", fd, ancestors); element != nil {
+				items = append(items, &parquetSchemaItem{
+					Tag:    name + ", type=LIST, repetitiontype=OPTIONAL",
+					Fields: []*parquetSchemaItem{element},
+				})
+			}
+		default:
+			if item := parquetField(name, "OPTIONAL", fd, ancestors); item != nil {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+// parquetField returns the schema of a single value of a given field, or nil for messages without fields.
+func parquetField(name string, repetition string, fd protoreflect.FieldDescriptor, ancestors map[protoreflect.FullName]bool) *parquetSchemaItem {
+	tag := func(typ string) *parquetSchemaItem {
+		return &parquetSchemaItem{Tag: fmt.Sprintf("%s, type=%s, repetitiontype=%s", name, typ, repetition)}
+	}
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return tag("BOOLEAN")
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return tag("INT32")
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return tag("INT64")
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		switch {
+		case timestampFields[string(fd.Name())]:
+			return tag("INT64, convertedtype=TIMESTAMP_MILLIS")
+		case fd.Kind() == protoreflect.Uint64Kind || fd.Kind() == protoreflect.Fixed64Kind:
+			return tag("INT64, convertedtype=UINT_64")
+		}
+		return tag("INT64")
+	case protoreflect.FloatKind:
+		return tag("FLOAT")
+	case protoreflect.DoubleKind:
+		return tag("DOUBLE")
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		md := fd.Message()
+		if ancestors[md.FullName()] {
+			return tag("BYTE_ARRAY, convertedtype=UTF8")
+		}
+		ancestors[md.FullName()] = true
+		defer delete(ancestors, md.FullName())
+		fields := parquetFields(md, ancestors)
+		if len(fields) == 0 {
+			return nil
+		}
+		return &parquetSchemaItem{Tag: fmt.Sprintf("%s, repetitiontype=%s", name, repetition), Fields: fields}
+	}
+	// Strings, bytes (as base64) and enums (by name)
+	return tag("BYTE_ARRAY, convertedtype=UTF8")
+}
+
+// parquetRow returns the JSON representation of a message that matches its Parquet schema.
+func parquetRow(m protoreflect.Message) ([]byte, error) {
+	return json.Marshal(parquetValues(m, map[protoreflect.FullName]bool{m.Descriptor().FullName(): true}))
+}
+
+func parquetValues(m protoreflect.Message, ancestors map[protoreflect.FullName]bool) map[string]interface{} {
+	row := make(map[string]interface{})
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.HasPresence() && !m.Has(fd) {
+			continue
+		}
+		v := m.Get(fd)
+		switch {
+		case fd.IsMap():
+			entries := make(map[string]interface{})
+			v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				entries[k.String()] = parquetValue(fd.MapValue(), v, ancestors)
+				return true
+			})
+			row[string(fd.Name())] = entries
+		case fd.IsList():
+			list := v.List()
+			items := make([]interface{}, 0, list.Len())
+			for j := 0; j < list.Len(); j++ {
+				items = append(items, parquetValue(fd, list.Get(j), ancestors))
+			}
+			row[string(fd.Name())] = items
+		default:
+			row[string(fd.Name())] = parquetValue(fd, v, ancestors)
+		}
+	}
+	return row
+}
+
+func parquetValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, ancestors map[protoreflect.FullName]bool) interface{} {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		name := fd.Message().FullName()
+		if ancestors[name] {
+			data, _ := protojson.MarshalOptions{UseProtoNames: true}.Marshal(v.Message().Interface())
+			return string(data)
+		}
+		ancestors[name] = true
+		defer delete(ancestors, name)
+		return parquetValues(v.Message(), ancestors)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return fmt.Sprint(v.Enum())
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		if f := v.Float(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+		return nil
+	}
+	return v.Interface()
+}
+
+type parquetFile struct {
+	path       string // Final path; the file is written with a temporary suffix until it is closed
+	file       *os.File
+	writer     *writer.JSONWriter
+	size       int64
+	created    time.Time
+	deliveries []*delivery
+}
+
+// ParquetSink writes rows to Parquet files partitioned by kind, date and hour (i.e. kind=alarm/date=2020-09-13/hour=12),
+// rotating them by size and time. The deliveries of the source messages are released only after the files with their rows are closed.
+type ParquetSink struct {
+	Directory string
+	MaxSize   int64         // Uncompressed size of the rows in bytes; zero means no limit
+	Interval  time.Duration // Zero means no time-based rotation
+	Release   func(deliveries []*delivery)
+	Fail      func(err error) // Called when a file cannot be closed, as its deliveries are never released
+	mutex     sync.Mutex
+	schemas   map[string]string
+	files     map[string]*parquetFile
+	sequence  int
+	done      chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewParquetSink creates a sink for a given directory and list of kinds, creating the directory if necessary.
+func NewParquetSink(directory string, kinds []string, maxSize int64, interval time.Duration) (*ParquetSink, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create parquet directory %s: %v", directory, err)
+	}
+	s := &ParquetSink{
+		Directory: directory,
+		MaxSize:   maxSize,
+		Interval:  interval,
+		schemas:   make(map[string]string),
+		files:     make(map[string]*parquetFile),
+	}
+	for _, kind := range kinds {
+		if kind == metricKind {
+			s.schemas[kind] = sampleParquetSchema
+			continue
+		}
+		schema, err := parquetSchema(proto.MessageReflect(newMessage(kind)).Descriptor())
+		if err != nil {
+			return nil, fmt.Errorf("cannot build parquet schema for %s: %v", kind, err)
+		}
+		s.schemas[kind] = schema
+	}
+	return s, nil
+}
+
+// Accepts returns true if the rows of a given kind are written by the sink.
+func (s *ParquetSink) Accepts(kind string) bool {
+	_, ok := s.schemas[kind]
+	return ok
+}
+
+// Start starts the background routine that closes the files older than the rotation interval.
+func (s *ParquetSink) Start() {
+	s.done = make(chan struct{})
+	if s.Interval <= 0 {
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(parquetCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.rotate(time.Now())
+			case <-s.done:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the background routine and closes all the files.
+func (s *ParquetSink) Stop() {
+	if s.done != nil {
+		close(s.done)
+		s.wg.Wait()
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, f := range s.files {
+		s.close(key, f)
+	}
+}
+
+// Write adds a JSON row of a given kind to the file of the partition for a given time.
+// The delivery, if any, is released once the file is closed.
+func (s *ParquetSink) Write(kind string, ts time.Time, row []byte, d *delivery) error {
+	schema, ok := s.schemas[kind]
+	if !ok {
+		return fmt.Errorf("unsupported parquet kind %s", kind)
+	}
+	ts = ts.UTC()
+	key := filepath.Join("kind="+kind, "date="+ts.Format("2006-01-02"), "hour="+ts.Format("15"))
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f, ok := s.files[key]
+	if ok && s.MaxSize > 0 && f.size > 0 && f.size+int64(len(row)) > s.MaxSize {
+		s.close(key, f)
+		ok = false
+	}
+	if !ok {
+		var err error
+		if f, err = s.create(key, kind, schema); err != nil {
+			return err
+		}
+		s.files[key] = f
+	}
+	if err := f.writer.Write(string(row)); err != nil {
+		return fmt.Errorf("cannot write %s row: %v", kind, err)
+	}
+	f.size += int64(len(row))
+	if d != nil {
+		f.deliveries = append(f.deliveries, d)
+	}
+	return nil
+}
+
+// rotate closes the files older than the rotation interval.
+func (s *ParquetSink) rotate(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, f := range s.files {
+		if now.Sub(f.created) >= s.Interval {
+			s.close(key, f)
+		}
+	}
+}
+
+func (s *ParquetSink) create(key string, kind string, schema string) (*parquetFile, error) {
+	dir := filepath.Join(s.Directory, key)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("cannot create parquet directory %s: %v", dir, err)
+	}
+	now := time.Now()
+	s.sequence++
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s-%d.parquet", kind, now.UTC().Format("20060102T150405"), s.sequence))
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create parquet file: %v", err)
+	}
+	w, err := writer.NewJSONWriterFromWriter(schema, file, 1)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, fmt.Errorf("cannot create parquet writer for %s: %v", kind, err)
+	}
+	w.RowGroupSize = parquetRowGroupSize
+	return &parquetFile{path: path, file: file, writer: w, created: now}, nil
+}
+
+// close writes the footer of a file, and releases its deliveries; the mutex must be held.
+// On failure, the deliveries are not released, so the source messages are processed again after a restart, and Fail is called.
+func (s *ParquetSink) close(key string, f *parquetFile) {
+	delete(s.files, key)
+	err := f.writer.WriteStop()
+	if cerr := f.file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.file.Name(), f.path)
+	}
+	if err != nil {
+		err = fmt.Errorf("cannot close parquet file %s: %v", f.path, err)
+		if s.Fail != nil {
+			s.Fail(err)
+		} else {
+			log.Printf("%v\n", err)
+		}
+		return
+	}
+	if s.Release != nil && len(f.deliveries) > 0 {
+		s.Release(f.deliveries)
+	}
+}
+
+// processParquet writes the rows derived from a source message, tracking them like the produced records.
+func (cli *KafkaClient) processParquet(msg *kafka.Message, kind string, data proto.Message) {
+	if !cli.parquet.Accepts(kind) {
+		return
+	}
+	ts := msg.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	rows := make([][]byte, 0)
+	if cs, ok := data.(*producer.CollectionSet); ok {
+		for _, record := range explodeCollectionSet(cs, explodeBySample) {
+			row, err := json.Marshal(record.Value)
+			if err != nil {
+				log.Printf("cannot convert sample for resource %s: %v\n", record.Key, err)
+				continue
+			}
+			rows = append(rows, row)
+		}
+	} else {
+		row, err := parquetRow(proto.MessageReflect(data))
+		if err != nil {
+			cli.deadLetter(msg, kind, fmt.Errorf("cannot convert GPB to parquet: %v", err))
+			return
+		}
+		rows = append(rows, row)
+	}
+	for _, row := range rows {
+		d, ok := msg.Opaque.(*delivery)
+		if !ok || !cli.tracker.Add(d) {
+			d = nil
+		}
+		if err := cli.parquet.Write(kind, ts, row, d); err != nil {
+			// The dead-letter record keeps the source message in flight until it is delivered
+			cli.deadLetter(msg, kind, err)
+			if d != nil {
+				cli.tracker.Done(d)
+			}
+			return
+		}
+	}
+}
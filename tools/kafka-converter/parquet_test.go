@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/proto"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+	"gotest.tools/assert"
+)
+
+func readParquet(t *testing.T, path string) []map[string]interface{} {
+	f, err := local.NewLocalFileReader(path)
+	assert.NilError(t, err)
+	defer f.Close()
+	pr, err := reader.NewParquetReader(f, nil, 1)
+	assert.NilError(t, err)
+	defer pr.ReadStop()
+	rows, err := pr.ReadByNumber(int(pr.GetNumRows()))
+	assert.NilError(t, err)
+	data, err := json.Marshal(rows)
+	assert.NilError(t, err)
+	result := make([]map[string]interface{}, 0)
+	assert.NilError(t, json.Unmarshal(data, &result))
+	return result
+}
+
+func TestParquetSchema(t *testing.T) {
+	schema, err := parquetSchema(proto.MessageReflect(&producer.Alarm{}).Descriptor())
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(schema, `"name=first_event_time, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=OPTIONAL"`))
+	assert.Assert(t, strings.Contains(schema, `"name=count, type=INT64, convertedtype=UINT_64, repetitiontype=OPTIONAL"`))
+	assert.Assert(t, strings.Contains(schema, `"name=severity, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`))
+	// Nested messages are groups, and recursive ones are JSON strings
+	assert.Assert(t, strings.Contains(schema, `{"Tag":"name=last_event, repetitiontype=OPTIONAL","Fields":[`))
+	assert.Assert(t, strings.Contains(schema, `{"Tag":"name=relatedAlarm, type=LIST, repetitiontype=OPTIONAL","Fields":[{"Tag":"name=element, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=REQUIRED"}]}`))
+
+	for _, kind := range []string{alarmKind, eventKind, nodeKind} {
+		_, err := NewParquetSink(t.TempDir(), []string{kind}, 0, 0)
+		assert.NilError(t, err, kind)
+	}
+}
+
+func TestParquetRow(t *testing.T) {
+	alarm := &producer.Alarm{
+		Id:             1,
+		Uei:            "uei.opennms.org/nodes/nodeDown",
+		Severity:       producer.Severity_MAJOR,
+		FirstEventTime: 1600000000000,
+		NodeCriteria:   &producer.NodeCriteria{Id: 1, ForeignSource: "Test", ForeignId: "srv01"},
+		RelatedAlarm:   []*producer.Alarm{{Id: 2}},
+	}
+	row, err := parquetRow(proto.MessageReflect(alarm))
+	assert.NilError(t, err)
+	var content map[string]interface{}
+	assert.NilError(t, json.Unmarshal(row, &content))
+	assert.Equal(t, "MAJOR", content["severity"])
+	assert.Equal(t, float64(1600000000000), content["first_event_time"])
+	assert.Equal(t, "srv01", content["node_criteria"].(map[string]interface{})["foreign_id"])
+	assert.DeepEqual(t, []interface{}{`{"id":"2"}`}, content["relatedAlarm"])
+	_, ok := content["last_event"]
+	assert.Assert(t, !ok)
+}
+
+func TestParquetSink(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewParquetSink(dir, []string{alarmKind, metricKind}, 0, time.Minute)
+	assert.NilError(t, err)
+	released := make([]*delivery, 0)
+	s.Release = func(deliveries []*delivery) {
+		released = append(released, deliveries...)
+	}
+	assert.Assert(t, s.Accepts(alarmKind))
+	assert.Assert(t, !s.Accepts(eventKind))
+
+	ts := time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)
+	topic := "alarms"
+	d := &delivery{partition: kafka.TopicPartition{Topic: &topic, Offset: 1}}
+	for _, alarm := range []*producer.Alarm{
+		{Id: 1, Uei: "uei.opennms.org/nodes/nodeDown", Severity: producer.Severity_MAJOR, FirstEventTime: 1600000000000},
+		{Id: 2, Uei: "uei.opennms.org/nodes/nodeUp", Severity: producer.Severity_CLEARED, RelatedAlarm: []*producer.Alarm{{Id: 1}}},
+	} {
+		row, err := parquetRow(proto.MessageReflect(alarm))
+		assert.NilError(t, err)
+		assert.NilError(t, s.Write(alarmKind, ts, row, d))
+	}
+	cs := &producer.CollectionSet{
+		Timestamp: 1600000000000,
+		Resource: []*producer.CollectionSetResource{{
+			Resource: &producer.CollectionSetResource_Node{Node: &producer.NodeLevelResource{NodeId: 1, NodeLabel: "srv01"}},
+			Numeric:  []*producer.NumericAttribute{{Group: "cpu", Name: "idle", Value: 99, Type: producer.NumericAttribute_GAUGE}},
+		}},
+	}
+	for _, record := range explodeCollectionSet(cs, explodeBySample) {
+		row, err := json.Marshal(record.Value)
+		assert.NilError(t, err)
+		assert.NilError(t, s.Write(metricKind, ts.Add(time.Hour), row, nil))
+	}
+	assert.Equal(t, 0, len(released))
+
+	// Files are renamed and the deliveries released only when they are closed
+	s.rotate(time.Now())
+	assert.Equal(t, 0, len(released))
+	s.rotate(time.Now().Add(time.Minute))
+	assert.Equal(t, 2, len(released))
+
+	alarms, err := filepath.Glob(filepath.Join(dir, "kind=alarm", "date=2020-09-13", "hour=12", "alarm-*.parquet"))
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(alarms))
+	rows := readParquet(t, alarms[0])
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, "MAJOR", rows[0]["Severity"])
+	assert.Equal(t, float64(1600000000000), rows[0]["First_event_time"])
+	assert.DeepEqual(t, []interface{}{`{"id":"1"}`}, rows[1]["RelatedAlarm"])
+
+	samples, err := filepath.Glob(filepath.Join(dir, "kind=metric", "date=2020-09-13", "hour=13", "metric-*.parquet"))
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(samples))
+	rows = readParquet(t, samples[0])
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, "srv01", rows[0]["Node_label"])
+	assert.Equal(t, "idle", rows[0]["Name"])
+	assert.Equal(t, float64(99), rows[0]["Value"])
+
+	tmp, _ := filepath.Glob(filepath.Join(dir, "*", "*", "*", "*.tmp"))
+	assert.Equal(t, 0, len(tmp))
+	s.Stop()
+}
+
+func TestParquetSinkRotateBySize(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewParquetSink(dir, []string{eventKind}, 100, 0)
+	assert.NilError(t, err)
+	ts := time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		row, err := parquetRow(proto.MessageReflect(&producer.Event{Id: uint64(i), Uei: "uei.opennms.org/internal/discovery/newSuspect"}))
+		assert.NilError(t, err)
+		assert.NilError(t, s.Write(eventKind, ts, row, nil))
+	}
+	s.Stop()
+	files, err := filepath.Glob(filepath.Join(dir, "kind=event", "date=2020-09-13", "hour=12", "*.parquet"))
+	assert.NilError(t, err)
+	assert.Assert(t, len(files) > 1)
+	count := 0
+	for _, f := range files {
+		count += len(readParquet(t, f))
+	}
+	assert.Equal(t, 3, count)
+}
+
+func TestParquetSinkFailures(t *testing.T) {
+	// A file that cannot be closed is reported, and its deliveries are not released
+	dir := t.TempDir()
+	s, err := NewParquetSink(dir, []string{eventKind}, 0, 0)
+	assert.NilError(t, err)
+	released := 0
+	s.Release = func(deliveries []*delivery) { released += len(deliveries) }
+	var failure error
+	s.Fail = func(err error) { failure = err }
+	topic := "events"
+	d := &delivery{partition: kafka.TopicPartition{Topic: &topic, Offset: 1}}
+	row, err := parquetRow(proto.MessageReflect(&producer.Event{Id: 1, Uei: "uei.opennms.org/internal/discovery/newSuspect"}))
+	assert.NilError(t, err)
+	ts := time.Date(2020, 9, 13, 12, 26, 40, 0, time.UTC)
+	assert.NilError(t, s.Write(eventKind, ts, row, d))
+	assert.NilError(t, os.RemoveAll(filepath.Join(dir, "kind=event")))
+	s.Stop()
+	assert.ErrorContains(t, failure, "cannot close parquet file")
+	assert.Equal(t, 0, released)
+
+	// Messages whose rows cannot be written are sent to the dead-letter topic, which keeps them in flight
+	dir = t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "kind=event"), []byte{}) // Blocks the partition directory
+	output := t.TempDir()
+	sink, err := NewFileSink(output, 0, 0)
+	assert.NilError(t, err)
+	stored := 0
+	cli := &KafkaClient{
+		DeadLetterTopic: "dlq",
+		sink:            sink,
+		tracker: NewOffsetTracker(func(offsets []kafka.TopicPartition) ([]kafka.TopicPartition, error) {
+			stored++
+			return offsets, nil
+		}),
+	}
+	cli.parquet, err = NewParquetSink(dir, []string{eventKind}, 0, 0)
+	assert.NilError(t, err)
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: 1}, Timestamp: ts, Value: []byte{}}
+	msg.Opaque = cli.tracker.Begin(msg)
+	cli.processParquet(msg, eventKind, &producer.Event{Id: 1})
+	cli.tracker.Seal(msg.Opaque.(*delivery))
+	sink.Close()
+	assert.Equal(t, 0, stored)
+	content := readSinkFiles(t, output)
+	assert.Assert(t, content["dlq"] != "")
+}
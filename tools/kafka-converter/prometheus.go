@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+)
+
+// The prefix used for all the metrics exposed to Prometheus.
+const metricPrefix = "opennms_"
+
+var invalidMetricChars = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+
+// metricName builds a valid Prometheus metric name for a given numeric attribute.
+func metricName(group, name string) string {
+	return metricPrefix + invalidMetricChars.ReplaceAllString(group+"_"+name, "_")
+}
+
+// labelsString returns the labels in the Prometheus exposition format sorted by name.
+func labelsString(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = k + `="` + escapeLabelValue(labels[k]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func escapeLabelValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
+
+// promSample represents the latest known value of a given time series.
+type promSample struct {
+	name      string
+	labels    string
+	counter   bool
+	value     float64
+	updatedAt time.Time
+}
+
+// PrometheusExporter keeps the latest samples from CollectionSet messages and exposes them through HTTP.
+type PrometheusExporter struct {
+	TTL     time.Duration
+	mutex   sync.Mutex
+	samples map[string]*promSample
+	now     func() time.Time
+}
+
+// NewPrometheusExporter creates a new exporter; series not updated within the TTL are discarded.
+func NewPrometheusExporter(ttl time.Duration) *PrometheusExporter {
+	return &PrometheusExporter{
+		TTL:     ttl,
+		samples: make(map[string]*promSample),
+		now:     time.Now,
+	}
+}
+
+// Update registers all the numeric attributes of a given collection set.
+func (exp *PrometheusExporter) Update(cs *producer.CollectionSet) {
+	exp.mutex.Lock()
+	defer exp.mutex.Unlock()
+	now := exp.now()
+	for _, resource := range cs.Resource {
+		labels := resolveResource(resource).Labels()
+		for _, attr := range resource.Numeric {
+			sample := &promSample{
+				name:      metricName(attr.Group, attr.Name),
+				labels:    labelsString(labels),
+				counter:   attr.Type == producer.NumericAttribute_COUNTER,
+				value:     attr.Value,
+				updatedAt: now,
+			}
+			exp.samples[sample.name+sample.labels] = sample
+		}
+	}
+}
+
+// expire removes the samples not updated within the TTL; the mutex must be held.
+func (exp *PrometheusExporter) expire() {
+	if exp.TTL <= 0 {
+		return
+	}
+	deadline := exp.now().Add(-exp.TTL)
+	for key, sample := range exp.samples {
+		if sample.updatedAt.Before(deadline) {
+			delete(exp.samples, key)
+		}
+	}
+}
+
+// Write writes all the active samples in the Prometheus text exposition format.
+func (exp *PrometheusExporter) Write(w io.Writer) error {
+	exp.mutex.Lock()
+	exp.expire()
+	samples := make([]*promSample, 0, len(exp.samples))
+	for _, sample := range exp.samples {
+		samples = append(samples, sample)
+	}
+	exp.mutex.Unlock()
+
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].name == samples[j].name {
+			return samples[i].labels < samples[j].labels
+		}
+		return samples[i].name < samples[j].name
+	})
+	lastName := ""
+	for _, sample := range samples {
+		if sample.name != lastName {
+			metricType := "gauge"
+			if sample.counter {
+				metricType = "counter"
+			}
+			if _, err := fmt.Fprintf(w, "# TYPE %s %s\n", sample.name, metricType); err != nil {
+				return err
+			}
+			lastName = sample.name
+		}
+		value := strconv.FormatFloat(sample.value, 'g', -1, 64)
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", sample.name, sample.labels, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (exp *PrometheusExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := exp.Write(w); err != nil {
+		log.Printf("cannot write metrics: %v\n", err)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"gotest.tools/assert"
+)
+
+func TestPrometheusExporter(t *testing.T) {
+	now := time.Now()
+	exp := NewPrometheusExporter(time.Minute)
+	exp.now = func() time.Time { return now }
+	exp.Update(&producer.CollectionSet{
+		Timestamp: 1600000000000,
+		Resource: []*producer.CollectionSetResource{
+			{
+				Resource: &producer.CollectionSetResource_Interface{
+					Interface: &producer.InterfaceLevelResource{
+						Node: &producer.NodeLevelResource{
+							NodeId:        1,
+							ForeignSource: "Test",
+							ForeignId:     "001",
+							NodeLabel:     "srv01",
+							Location:      "Default",
+						},
+						Instance: "eth0-001122334455",
+					},
+				},
+				Numeric: []*producer.NumericAttribute{
+					{Group: "mib2-X-interfaces", Name: "ifHCInOctets", Value: 1000, Type: producer.NumericAttribute_COUNTER},
+					{Group: "mib2-X-interfaces", Name: "ifHighSpeed", Value: 1000},
+				},
+			},
+		},
+	})
+
+	var buf bytes.Buffer
+	assert.NilError(t, exp.Write(&buf))
+	labels := `{foreign_id="001",foreign_source="Test",instance="eth0-001122334455",location="Default",node_id="1",node_label="srv01",resource_type="interface"}`
+	expected := "# TYPE opennms_mib2_X_interfaces_ifHCInOctets counter\n" +
+		"opennms_mib2_X_interfaces_ifHCInOctets" + labels + " 1000\n" +
+		"# TYPE opennms_mib2_X_interfaces_ifHighSpeed gauge\n" +
+		"opennms_mib2_X_interfaces_ifHighSpeed" + labels + " 1000\n"
+	assert.Equal(t, expected, buf.String())
+
+	now = now.Add(2 * time.Minute)
+	buf.Reset()
+	assert.NilError(t, exp.Write(&buf))
+	assert.Equal(t, "", buf.String())
+}
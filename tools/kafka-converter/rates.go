@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Maximum values of the counters, used to detect wraps.
+const (
+	maxCounter32 = float64(1 << 32)
+	maxCounter64 = float64(1 << 64)
+)
+
+// CounterState represents the last known sample of a counter.
+type CounterState struct {
+	Value     float64 `json:"value"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+// RateConverter replaces the COUNTER numeric attributes of a CollectionSet with per-second rates.
+// The state of each series (resource, group and name) can be restored from a compacted topic or a snapshot file.
+type RateConverter struct {
+	StateTopic       string
+	SnapshotFile     string
+	SnapshotInterval time.Duration
+	mutex            sync.Mutex
+	state            map[string]*CounterState
+	reader           *TopicReader
+	stop             chan struct{}
+	wg               sync.WaitGroup
+}
+
+// NewRateConverter creates a new converter without state.
+func NewRateConverter() *RateConverter {
+	return &RateConverter{state: make(map[string]*CounterState)}
+}
+
+// Start restores the state from the snapshot file and the state topic, and starts saving snapshots periodically.
+func (c *RateConverter) Start(config *kafka.ConfigMap) error {
+	if c.SnapshotFile != "" {
+		if err := c.load(); err != nil {
+			return err
+		}
+	}
+	if c.StateTopic != "" {
+		c.reader = &TopicReader{Topic: c.StateTopic, Handler: c.updateState}
+		if err := c.reader.Start(config); err != nil {
+			return err
+		}
+	}
+	log.Printf("counter state initialized with %d series\n", c.Size())
+	if c.SnapshotFile != "" && c.SnapshotInterval > 0 {
+		c.stop = make(chan struct{})
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			ticker := time.NewTicker(c.SnapshotInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					if err := c.save(); err != nil {
+						log.Printf("cannot save counter state: %v\n", err)
+					}
+				case <-c.stop:
+					return
+				}
+			}
+		}()
+	}
+	return nil
+}
+
+// Stop stops reading the state topic, and saves the final snapshot.
+func (c *RateConverter) Stop() {
+	if c.stop != nil {
+		close(c.stop)
+		c.wg.Wait()
+	}
+	if c.reader != nil {
+		c.reader.Stop()
+	}
+	if c.SnapshotFile != "" {
+		if err := c.save(); err != nil {
+			log.Printf("cannot save counter state: %v\n", err)
+		}
+	}
+}
+
+// Size returns the number of series with state.
+func (c *RateConverter) Size() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.state)
+}
+
+// Convert replaces the counters of a given CollectionSet with rates, and returns the updated state per series.
+// The first sample of a series, as well as samples after a reset or out of order, are removed.
+func (c *RateConverter) Convert(cs *producer.CollectionSet) map[string]CounterState {
+	updates := make(map[string]CounterState)
+	for _, resource := range cs.Resource {
+		id := resolveResource(resource).ID()
+		numeric := make([]*producer.NumericAttribute, 0, len(resource.Numeric))
+		for _, attr := range resource.Numeric {
+			if attr.Type != producer.NumericAttribute_COUNTER {
+				numeric = append(numeric, attr)
+				continue
+			}
+			key := id + "/" + attr.Group + "/" + attr.Name
+			current := CounterState{Value: attr.Value, Timestamp: cs.Timestamp}
+			rate, updated, ok := c.rate(key, current)
+			if updated {
+				updates[key] = current
+			}
+			if ok {
+				attr.Value = rate
+				attr.Type = producer.NumericAttribute_GAUGE
+				numeric = append(numeric, attr)
+			}
+		}
+		resource.Numeric = numeric
+	}
+	return updates
+}
+
+// rate returns the per-second rate of a series based on its last known sample, and if the state was updated.
+func (c *RateConverter) rate(key string, current CounterState) (float64, bool, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	last, ok := c.state[key]
+	if ok && current.Timestamp <= last.Timestamp {
+		return 0, false, false
+	}
+	c.state[key] = &current
+	if !ok {
+		return 0, true, false
+	}
+	delta, ok := counterDelta(last.Value, current.Value)
+	if !ok {
+		return 0, true, false
+	}
+	return delta * 1000 / float64(current.Timestamp-last.Timestamp), true, true
+}
+
+// counterDelta returns the difference between two samples of a counter, taking wraps at 32 and 64 bits into account.
+// Returns false when the counter was reset.
+func counterDelta(last, current float64) (float64, bool) {
+	if current >= last {
+		return current - last, true
+	}
+	max := maxCounter64
+	if last < maxCounter32 {
+		max = maxCounter32
+	}
+	delta := max - last + current
+	if delta > max/2 {
+		// A wrap that big is more likely a reset
+		return 0, false
+	}
+	return delta, true
+}
+
+func (c *RateConverter) updateState(msg *kafka.Message) {
+	key := string(msg.Key)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(msg.Value) == 0 {
+		delete(c.state, key)
+		return
+	}
+	state := &CounterState{}
+	if err := json.Unmarshal(msg.Value, state); err != nil {
+		log.Printf("invalid counter state received from %s: %v\n", msg.TopicPartition, err)
+		return
+	}
+	if last, ok := c.state[key]; !ok || state.Timestamp > last.Timestamp {
+		c.state[key] = state
+	}
+}
+
+func (c *RateConverter) load() error {
+	data, err := ioutil.ReadFile(c.SnapshotFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read counter state file %s: %v", c.SnapshotFile, err)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if err := json.Unmarshal(data, &c.state); err != nil {
+		return fmt.Errorf("cannot parse counter state file %s: %v", c.SnapshotFile, err)
+	}
+	return nil
+}
+
+// save writes the state to a temporary file, and then renames it, to avoid corrupting the snapshot.
+func (c *RateConverter) save() error {
+	c.mutex.Lock()
+	data, err := json.Marshal(c.state)
+	c.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp := c.SnapshotFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.SnapshotFile)
+}
+
+func (cli *KafkaClient) processRates(msg *kafka.Message, cs *producer.CollectionSet) {
+	updates := cli.rates.Convert(cs)
+	if cli.RateStateTopic == "" {
+		return
+	}
+	for key, state := range updates {
+		value, err := json.Marshal(state)
+		if err != nil {
+			log.Printf("cannot convert counter state for %s: %v\n", key, err)
+			continue
+		}
+		cli.produce(msg, cli.RateStateTopic, []byte(key), value)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"gotest.tools/assert"
+)
+
+func buildCounterSet(ts int64, value float64) *producer.CollectionSet {
+	return &producer.CollectionSet{
+		Timestamp: ts,
+		Resource: []*producer.CollectionSetResource{
+			{
+				Resource: &producer.CollectionSetResource_Node{Node: &producer.NodeLevelResource{NodeId: 1}},
+				Numeric: []*producer.NumericAttribute{
+					{Group: "tcp", Name: "tcpInSegs", Value: value, Type: producer.NumericAttribute_COUNTER},
+					{Group: "tcp", Name: "tcpCurrEstab", Value: 10, Type: producer.NumericAttribute_GAUGE},
+				},
+			},
+		},
+	}
+}
+
+func TestCounterDelta(t *testing.T) {
+	delta, ok := counterDelta(100, 400)
+	assert.Assert(t, ok)
+	assert.Equal(t, 300.0, delta)
+
+	delta, ok = counterDelta(maxCounter32-100, 200)
+	assert.Assert(t, ok)
+	assert.Equal(t, 300.0, delta)
+
+	delta, ok = counterDelta(maxCounter64-4096, 1024)
+	assert.Assert(t, ok)
+	assert.Equal(t, 5120.0, delta)
+
+	_, ok = counterDelta(1000000, 10)
+	assert.Assert(t, !ok)
+}
+
+func TestRateConverter(t *testing.T) {
+	c := NewRateConverter()
+
+	// The first sample is dropped
+	cs := buildCounterSet(1000000, 1000)
+	updates := c.Convert(cs)
+	assert.Equal(t, 1, len(updates))
+	assert.Equal(t, 1, len(cs.Resource[0].Numeric))
+	assert.Equal(t, "tcpCurrEstab", cs.Resource[0].Numeric[0].Name)
+
+	cs = buildCounterSet(1030000, 4000)
+	c.Convert(cs)
+	assert.Equal(t, 2, len(cs.Resource[0].Numeric))
+	assert.Equal(t, 100.0, cs.Resource[0].Numeric[0].Value)
+	assert.Equal(t, producer.NumericAttribute_GAUGE, cs.Resource[0].Numeric[0].Type)
+
+	// Out of order samples are dropped without updating the state
+	cs = buildCounterSet(1020000, 5000)
+	assert.Equal(t, 0, len(c.Convert(cs)))
+	assert.Equal(t, 1, len(cs.Resource[0].Numeric))
+
+	// Resets are dropped, and the new value becomes the reference
+	cs = buildCounterSet(1040000, 10)
+	c.Convert(cs)
+	assert.Equal(t, 1, len(cs.Resource[0].Numeric))
+	cs = buildCounterSet(1050000, 110)
+	c.Convert(cs)
+	assert.Equal(t, 10.0, cs.Resource[0].Numeric[0].Value)
+}
+
+func TestRateConverterSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rates")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	c := NewRateConverter()
+	c.SnapshotFile = filepath.Join(dir, "counters.json")
+	assert.NilError(t, c.Start(nil))
+	c.Convert(buildCounterSet(1000000, 1000))
+	c.Stop()
+
+	c = NewRateConverter()
+	c.SnapshotFile = filepath.Join(dir, "counters.json")
+	assert.NilError(t, c.Start(nil))
+	assert.Equal(t, 1, c.Size())
+	cs := buildCounterSet(1010000, 2000)
+	c.Convert(cs)
+	assert.Equal(t, 100.0, cs.Resource[0].Numeric[0].Value)
+	c.Stop()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// TopicReader reads all the partitions of a topic from the beginning, and then keeps reading new messages.
+// It is meant to be used to build in-memory tables from compacted topics, so offsets are never committed.
+// When From is set, the partitions are read from the given offsets instead of the beginning.
+// When Until is set, only the messages before the given offsets are read, and the reader stops after that.
+// When Once is set, the reader stops after reaching the end of all the partitions.
+type TopicReader struct {
+	Topic    string
+	Handler  func(msg *kafka.Message)
+	From     func(partitions []kafka.TopicPartition) ([]kafka.TopicPartition, error)
+	Until    func(partitions []kafka.TopicPartition) ([]kafka.TopicPartition, error)
+	Once     bool
+	consumer *kafka.Consumer
+	stop     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Start reads the topic until reaching the end of all its partitions, and then continues reading in the background.
+func (r *TopicReader) Start(config *kafka.ConfigMap) error {
+	var err error
+	config.SetKey("enable.auto.commit", false)
+	config.SetKey("enable.partition.eof", true)
+	if r.consumer, err = kafka.NewConsumer(config); err != nil {
+		return fmt.Errorf("could not create consumer for %s: %v", r.Topic, err)
+	}
+	metadata, err := r.consumer.GetMetadata(&r.Topic, false, 10000)
+	if err != nil {
+		r.consumer.Close()
+		return fmt.Errorf("cannot get metadata for %s: %v", r.Topic, err)
+	}
+	partitions := make([]kafka.TopicPartition, 0)
+	for _, p := range metadata.Topics[r.Topic].Partitions {
+		partitions = append(partitions, kafka.TopicPartition{Topic: &r.Topic, Partition: p.ID, Offset: kafka.OffsetBeginning})
+	}
+	if len(partitions) == 0 {
+		r.consumer.Close()
+		return fmt.Errorf("topic %s doesn't exist or has no partitions", r.Topic)
+	}
+	start := partitions
+	if r.From != nil {
+		if start, err = r.From(partitions); err != nil {
+			r.consumer.Close()
+			return fmt.Errorf("cannot get start offsets for %s: %v", r.Topic, err)
+		}
+	}
+	if err := r.consumer.Assign(start); err != nil {
+		r.consumer.Close()
+		return fmt.Errorf("cannot assign partitions for %s: %v", r.Topic, err)
+	}
+
+	// Read until reaching the end of all the partitions, or the given offsets
+	if r.From != nil {
+		log.Printf("reading %s from %v\n", r.Topic, start)
+	} else {
+		log.Printf("reading %s from the beginning\n", r.Topic)
+	}
+	pending := make(map[int32]bool, len(partitions))
+	for _, p := range partitions {
+		pending[p.Partition] = true
+	}
+	until := make(map[int32]kafka.Offset)
+	if r.Until != nil {
+		offsets, err := r.Until(partitions)
+		if err != nil {
+			r.consumer.Close()
+			return fmt.Errorf("cannot get offsets for %s: %v", r.Topic, err)
+		}
+		for _, tp := range offsets {
+			until[tp.Partition] = tp.Offset
+			if tp.Offset <= 0 {
+				delete(pending, tp.Partition)
+			}
+		}
+	}
+	count := 0
+	for len(pending) > 0 {
+		switch e := r.consumer.Poll(1000).(type) {
+		case *kafka.Message:
+			p := e.TopicPartition.Partition
+			if offset, ok := until[p]; ok && e.TopicPartition.Offset >= offset {
+				delete(pending, p)
+				continue
+			}
+			r.Handler(e)
+			count++
+			if offset, ok := until[p]; ok && e.TopicPartition.Offset+1 >= offset {
+				delete(pending, p)
+			}
+		case kafka.PartitionEOF:
+			delete(pending, e.Partition)
+		case kafka.Error:
+			log.Printf("kafka consumer error on %s: %v\n", r.Topic, e)
+		}
+	}
+	log.Printf("%d messages read from %s\n", count, r.Topic)
+	if r.Until != nil || r.Once {
+		r.consumer.Close()
+		r.consumer = nil
+		return nil
+	}
+
+	// Keep reading in the background
+	r.stop = make(chan struct{})
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		for {
+			select {
+			case <-r.stop:
+				return
+			default:
+			}
+			switch e := r.consumer.Poll(1000).(type) {
+			case *kafka.Message:
+				r.Handler(e)
+			case kafka.Error:
+				log.Printf("kafka consumer error on %s: %v\n", r.Topic, e)
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops reading the topic.
+func (r *TopicReader) Stop() {
+	if r.stop != nil {
+		close(r.stop)
+		r.wg.Wait()
+	}
+	if r.consumer != nil {
+		r.consumer.Close()
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// promLabel represents a Prometheus label.
+type promLabel struct {
+	name  string
+	value string
+}
+
+// promSeries represents a Prometheus time series with a single sample.
+type promSeries struct {
+	labels    []promLabel
+	value     float64
+	timestamp int64
+}
+
+// RemoteWriter pushes the samples from CollectionSet messages to a Prometheus remote_write endpoint.
+type RemoteWriter struct {
+	*Batcher
+	URL        string
+	MaxRetries int
+	client     *http.Client
+	backoff    time.Duration
+}
+
+// NewRemoteWriter creates a new remote writer for the given URL.
+func NewRemoteWriter(url string, batchSize int, flushInterval time.Duration, maxRetries int) *RemoteWriter {
+	rw := &RemoteWriter{
+		URL:        url,
+		MaxRetries: maxRetries,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		backoff:    time.Second,
+	}
+	rw.Batcher = NewBatcher("remote write "+url, batchSize, flushInterval, func(items []interface{}) error {
+		series := make([]promSeries, len(items))
+		for i, item := range items {
+			series[i] = item.(promSeries)
+		}
+		return rw.send(series)
+	})
+	return rw
+}
+
+// Add appends all the numeric attributes of a given collection set to the pending samples.
+func (rw *RemoteWriter) Add(cs *producer.CollectionSet) {
+	series := make([]interface{}, 0)
+	for _, resource := range cs.Resource {
+		labels := resolveResource(resource).Labels()
+		for _, attr := range resource.Numeric {
+			s := promSeries{
+				labels:    make([]promLabel, 0, len(labels)+1),
+				value:     attr.Value,
+				timestamp: cs.Timestamp,
+			}
+			s.labels = append(s.labels, promLabel{"__name__", metricName(attr.Group, attr.Name)})
+			for k, v := range labels {
+				s.labels = append(s.labels, promLabel{k, v})
+			}
+			sort.Slice(s.labels, func(i, j int) bool { return s.labels[i].name < s.labels[j].name })
+			series = append(series, s)
+		}
+	}
+	rw.Batcher.Add(series...)
+}
+
+// send pushes a WriteRequest with the given series, retrying on server errors.
+func (rw *RemoteWriter) send(series []promSeries) error {
+	body := snappy.Encode(nil, encodeWriteRequest(series))
+	backoff := rw.backoff
+	var err error
+	for attempt := 0; attempt <= rw.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		var retry bool
+		if retry, err = rw.post(body); err == nil || !retry {
+			return err
+		}
+		log.Printf("remote write attempt %d failed: %v\n", attempt+1, err)
+	}
+	return err
+}
+
+// post sends the payload, and returns whether the request can be retried in case of errors.
+func (rw *RemoteWriter) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, rw.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	res, err := rw.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 512))
+	if res.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("server returned HTTP status %s: %s", res.Status, bytes.TrimSpace(msg))
+	return res.StatusCode/100 == 5, err
+}
+
+// encodeWriteRequest builds a Prometheus WriteRequest protobuf message.
+// See https://github.com/prometheus/prometheus/blob/main/prompb/remote.proto
+func encodeWriteRequest(series []promSeries) []byte {
+	var req []byte
+	for _, s := range series {
+		var ts []byte
+		for _, l := range s.labels {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, l.name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, l.value)
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, label)
+		}
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(s.timestamp))
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sample)
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, ts)
+	}
+	return req
+}
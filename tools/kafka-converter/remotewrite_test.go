@@ -0,0 +1,116 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+	"gotest.tools/assert"
+)
+
+// decodeFields returns the raw bytes of all the length-delimited fields with the given number.
+func decodeFields(t *testing.T, data []byte, field protowire.Number) [][]byte {
+	result := make([][]byte, 0)
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		assert.Assert(t, n > 0)
+		data = data[n:]
+		if num == field && typ == protowire.BytesType {
+			v, m := protowire.ConsumeBytes(data)
+			result = append(result, v)
+			data = data[m:]
+		} else {
+			m := protowire.ConsumeFieldValue(num, typ, data)
+			assert.Assert(t, m > 0)
+			data = data[m:]
+		}
+	}
+	return result
+}
+
+func TestRemoteWriter(t *testing.T) {
+	requests := 0
+	var series [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NilError(t, err)
+		data, err := snappy.Decode(nil, body)
+		assert.NilError(t, err)
+		series = decodeFields(t, data, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	rw := NewRemoteWriter(server.URL, 10, time.Minute, 3)
+	rw.backoff = time.Millisecond
+	rw.Add(&producer.CollectionSet{
+		Timestamp: 1600000000000,
+		Resource: []*producer.CollectionSetResource{
+			{
+				Resource: &producer.CollectionSetResource_Node{Node: &producer.NodeLevelResource{NodeId: 1, NodeLabel: "srv01"}},
+				Numeric: []*producer.NumericAttribute{
+					{Group: "mib2-tcp", Name: "tcpActiveOpens", Value: 10, Type: producer.NumericAttribute_COUNTER},
+					{Group: "mib2-tcp", Name: "tcpCurrEstab", Value: 5},
+				},
+			},
+		},
+	})
+	assert.Equal(t, 0, requests)
+	rw.Stop()
+
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, 2, len(series))
+	labels := decodeFields(t, series[0], 1)
+	assert.Equal(t, 4, len(labels)) // __name__, node_id, node_label, resource_type
+	name := decodeFields(t, labels[0], 2)
+	assert.Equal(t, "opennms_mib2_tcp_tcpActiveOpens", string(name[0]))
+}
+
+func TestRemoteWriterQueue(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	rw := NewRemoteWriter(server.URL, 2, time.Minute, 0)
+	rw.queue = make(chan []interface{}, 1)
+	cs := &producer.CollectionSet{
+		Timestamp: 1600000000000,
+		Resource: []*producer.CollectionSetResource{
+			{
+				Resource: &producer.CollectionSetResource_Node{Node: &producer.NodeLevelResource{NodeId: 1}},
+				Numeric: []*producer.NumericAttribute{
+					{Group: "cpu", Name: "idle", Value: 99},
+					{Group: "cpu", Name: "user", Value: 1},
+					{Group: "cpu", Name: "system", Value: 0},
+				},
+			},
+		},
+	}
+
+	// Full batches are queued without blocking the caller, and dropped when the queue is full
+	rw.Add(cs)
+	assert.Equal(t, 1, len(rw.queue))
+	assert.Equal(t, 1, len(rw.pending))
+	rw.Add(cs)
+	assert.Equal(t, 1, len(rw.queue))
+	assert.Equal(t, 0, len(rw.pending))
+	assert.Equal(t, 0, requests)
+
+	rw.Stop()
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, 0, len(rw.queue))
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// RollupRecord represents the aggregation of the samples of a series within a window.
+type RollupRecord struct {
+	ResourceID string `json:"resource_id"`
+	Window     string `json:"window"`
+	Start      int64  `json:"window_start"`
+	End        int64  `json:"window_end"`
+	ResourceInfo
+	Group    string  `json:"group"`
+	Name     string  `json:"name"`
+	Count    int     `json:"count"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Avg      float64 `json:"avg"`
+	Last     float64 `json:"last"`
+	sum      float64
+	lastTime int64
+}
+
+func (r *RollupRecord) add(value float64, ts int64) {
+	if r.Count == 0 || value < r.Min {
+		r.Min = value
+	}
+	if r.Count == 0 || value > r.Max {
+		r.Max = value
+	}
+	if r.Count == 0 || ts >= r.lastTime {
+		r.Last = value
+		r.lastTime = ts
+	}
+	r.Count++
+	r.sum += value
+	r.Avg = r.sum / float64(r.Count)
+}
+
+// Rollup aggregates the numeric samples of CollectionSets into tumbling windows per series.
+type Rollup struct {
+	*TumblingWindow
+	Topic string
+}
+
+// NewRollup creates a new rollup for a given window size.
+func NewRollup(window time.Duration, topic string, lateness time.Duration) *Rollup {
+	return &Rollup{
+		TumblingWindow: NewTumblingWindow(window, lateness),
+		Topic:          topic,
+	}
+}
+
+// Add aggregates the samples of a given CollectionSet, and returns the windows closed as a result and their source messages.
+func (r *Rollup) Add(msg *kafka.Message, cs *producer.CollectionSet) ([]*RollupRecord, []*kafka.Message) {
+	win := r.open(msg, cs.Timestamp)
+	if win == nil {
+		if r.dropped%100 == 1 {
+			log.Printf("%d late samples dropped by the %s rollup\n", r.dropped, r.Window)
+		}
+		return nil, nil
+	}
+	for _, resource := range cs.Resource {
+		info := resolveResource(resource)
+		id := info.ID()
+		for _, attr := range resource.Numeric {
+			record := win.state(id+"/"+attr.Group+"/"+attr.Name, func() interface{} {
+				return &RollupRecord{
+					ResourceID:   id,
+					Window:       r.Window.String(),
+					Start:        win.start,
+					End:          win.end,
+					ResourceInfo: info,
+					Group:        attr.Group,
+					Name:         attr.Name,
+				}
+			}).(*RollupRecord)
+			record.add(attr.Value, cs.Timestamp)
+		}
+	}
+	states, sources := r.advance(cs.Timestamp)
+	records := make([]*RollupRecord, len(states))
+	for i, state := range states {
+		records[i] = state.(*RollupRecord)
+	}
+	return records, sources
+}
+
+// parseRollups parses a CSV of rollups, where each rollup has the following format: window:dest-topic
+func parseRollups(rollups string, lateness time.Duration) ([]*Rollup, error) {
+	list := make([]*Rollup, 0)
+	if strings.TrimSpace(rollups) == "" {
+		return list, nil
+	}
+	for _, entry := range strings.Split(rollups, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		array := strings.Split(entry, ":")
+		if len(array) != 2 || array[1] == "" {
+			return nil, fmt.Errorf("invalid rollup %s; expected window:dest-topic", entry)
+		}
+		window, err := time.ParseDuration(array[0])
+		if err != nil || window < time.Second {
+			return nil, fmt.Errorf("invalid rollup window %s; expected a duration of at least 1s", array[0])
+		}
+		list = append(list, NewRollup(window, array[1], lateness))
+	}
+	return list, nil
+}
+
+func (cli *KafkaClient) processRollups(msg *kafka.Message, cs *producer.CollectionSet) {
+	for _, rollup := range cli.rollups {
+		records, sources := rollup.Add(msg, cs)
+		cli.emitRollups(rollup.Topic, records, sources)
+	}
+}
+
+// emitRollups sends the records of the closed windows, releasing their source messages once all of them are delivered.
+func (cli *KafkaClient) emitRollups(topic string, records []*RollupRecord, sources []*kafka.Message) {
+	if len(sources) == 0 {
+		return
+	}
+	src := cli.groupDeliveries(sources)
+	defer cli.releaseDelivery(src)
+	for _, record := range records {
+		value, err := json.Marshal(record)
+		if err == nil && cli.JSONTimestamps {
+			value, err = convertTimestamps(value)
+		}
+		if err != nil {
+			log.Printf("cannot convert rollup for %s: %v\n", record.ResourceID, err)
+			continue
+		}
+		cli.produce(src, topic, []byte(record.ResourceID), value)
+		if cli.Debug {
+			log.Printf("rollup record: %s\n", string(value))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"gotest.tools/assert"
+)
+
+func buildGaugeSet(ts int64, value float64) *producer.CollectionSet {
+	return &producer.CollectionSet{
+		Timestamp: ts,
+		Resource: []*producer.CollectionSetResource{
+			{
+				Resource: &producer.CollectionSetResource_Node{Node: &producer.NodeLevelResource{NodeId: 1}},
+				Numeric:  []*producer.NumericAttribute{{Group: "tcp", Name: "tcpCurrEstab", Value: value}},
+			},
+		},
+	}
+}
+
+func TestRollup(t *testing.T) {
+	r := NewRollup(5*time.Minute, "metrics-5m", time.Minute)
+	held := 0
+	r.Hold = func(msg *kafka.Message) { held++ }
+	topic := "OpenNMS-metrics"
+	add := func(offset int64, ts int64, value float64) ([]*RollupRecord, []*kafka.Message) {
+		return r.Add(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Offset: kafka.Offset(offset)}}, buildGaugeSet(ts, value))
+	}
+	base := int64(1600000200000) // aligned to 5 minutes
+	closed, _ := add(1, base+60000, 10)
+	assert.Equal(t, 0, len(closed))
+	closed, _ = add(2, base+180000, 30)
+	assert.Equal(t, 0, len(closed))
+	closed, _ = add(3, base+120000, 20)
+	assert.Equal(t, 0, len(closed))
+
+	// The next window starts, but the first one is still open due to the allowed lateness
+	closed, _ = add(4, base+330000, 5)
+	assert.Equal(t, 0, len(closed))
+	closed, _ = add(5, base+240000, 40)
+	assert.Equal(t, 0, len(closed))
+
+	closed, sources := add(6, base+360000, 7)
+	assert.Equal(t, 1, len(closed))
+	record := closed[0]
+	assert.Equal(t, "1/node", record.ResourceID)
+	assert.Equal(t, "5m0s", record.Window)
+	assert.Equal(t, base, record.Start)
+	assert.Equal(t, base+300000, record.End)
+	assert.Equal(t, 4, record.Count)
+	assert.Equal(t, 10.0, record.Min)
+	assert.Equal(t, 40.0, record.Max)
+	assert.Equal(t, 25.0, record.Avg)
+	assert.Equal(t, 40.0, record.Last)
+
+	// The source messages of the closed window are returned, and the others are still held
+	offsets := make([]kafka.Offset, 0)
+	for _, src := range sources {
+		offsets = append(offsets, src.TopicPartition.Offset)
+	}
+	assert.DeepEqual(t, []kafka.Offset{1, 2, 3, 5}, offsets)
+	assert.Equal(t, 6, held)
+
+	// Samples for closed windows are dropped
+	closed, sources = add(7, base+270000, 100)
+	assert.Equal(t, 0, len(closed))
+	assert.Equal(t, 0, len(sources))
+	assert.Equal(t, 1, r.dropped)
+	assert.Equal(t, 6, held)
+}
+
+func TestParseRollups(t *testing.T) {
+	rollups, err := parseRollups("5m:metrics-5m, 1h:metrics-1h", time.Minute)
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(rollups))
+	assert.Equal(t, time.Hour, rollups[1].Window)
+	assert.Equal(t, "metrics-1h", rollups[1].Topic)
+
+	_, err = parseRollups("5x:metrics", time.Minute)
+	assert.ErrorContains(t, err, "invalid rollup window")
+	_, err = parseRollups("5m", time.Minute)
+	assert.ErrorContains(t, err, "invalid rollup")
+}
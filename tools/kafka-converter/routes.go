@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Route represents the conversion settings for a given source topic.
+type Route struct {
+	SourceTopic   string            `json:"source"`
+	MessageKind   string            `json:"kind"`
+	DestTopics    []string          `json:"dest"`
+	FlatDestTopic string            `json:"flat,omitempty"`
+	Filter        string            `json:"filter,omitempty"`     // Applied to all the destinations
+	Filters       map[string]string `json:"filters,omitempty"`    // Applied to a given destination, including the flat one
+	Transforms    map[string]string `json:"transforms,omitempty"` // Templates applied to a given destination, including the flat one
+	filter        *Filter
+	filters       map[string]*Filter
+	transforms    map[string]*Transform
+}
+
+func (r *Route) validate() error {
+	if r.SourceTopic == "" {
+		return fmt.Errorf("source topic cannot be empty")
+	}
+	if len(r.DestTopics) == 0 {
+		return fmt.Errorf("destination topic cannot be empty for %s", r.SourceTopic)
+	}
+	for _, t := range r.DestTopics {
+		if t == "" {
+			return fmt.Errorf("destination topic cannot be empty for %s", r.SourceTopic)
+		}
+	}
+	if r.MessageKind == "" {
+		return fmt.Errorf("message kind cannot be empty for %s", r.SourceTopic)
+	}
+	if r.MessageKind != autoKind && !isValidKind(r.MessageKind) && !isSinkKind(r.MessageKind) {
+		return fmt.Errorf("invalid message kind %s for %s. Valid options: %s, %s or %s", r.MessageKind, r.SourceTopic, strings.Join(kinds, ", "), strings.Join(sinkKinds, ", "), autoKind)
+	}
+	if isSinkKind(r.MessageKind) && (r.Filter != "" || len(r.Filters) > 0) {
+		return fmt.Errorf("filters are not supported for the %s messages on %s", r.MessageKind, r.SourceTopic)
+	}
+	var err error
+	if r.Filter != "" {
+		if r.filter, err = r.compileFilter(r.Filter); err != nil {
+			return fmt.Errorf("%v for %s", err, r.SourceTopic)
+		}
+	}
+	r.filters = make(map[string]*Filter, len(r.Filters))
+	for topic, expression := range r.Filters {
+		if !r.hasDestination(topic) {
+			return fmt.Errorf("filter for unknown destination %s on %s", topic, r.SourceTopic)
+		}
+		if r.filters[topic], err = r.compileFilter(expression); err != nil {
+			return fmt.Errorf("%v for %s", err, topic)
+		}
+	}
+	r.transforms = make(map[string]*Transform, len(r.Transforms))
+	for topic, text := range r.Transforms {
+		if !r.hasDestination(topic) {
+			return fmt.Errorf("transform for unknown destination %s on %s", topic, r.SourceTopic)
+		}
+		if r.transforms[topic], err = NewTransform(topic, text); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileFilter compiles a filter expression and verifies it against the message kind of the route.
+// With auto, the expression must be valid for at least one kind, and it cannot be evaluated against the others.
+func (r *Route) compileFilter(expression string) (*Filter, error) {
+	f, err := NewFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+	if r.MessageKind != autoKind {
+		return f, f.Check(r.MessageKind)
+	}
+	for _, kind := range kinds {
+		if f.Check(kind) == nil {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid filter %q: the identifiers and fields don't exist on any message kind", expression)
+}
+
+func (r *Route) hasDestination(topic string) bool {
+	return contains(r.DestTopics, topic) || topic == r.FlatDestTopic
+}
+
+// reshape applies the transform of a given destination topic to a JSON message, if any.
+func (r *Route) reshape(topic string, jsonBytes []byte) ([]byte, error) {
+	if t, ok := r.transforms[topic]; ok {
+		return t.Apply(jsonBytes)
+	}
+	return jsonBytes, nil
+}
+
+// accepts returns true if a given message should be sent to a given destination topic.
+// A filter that cannot be evaluated rejects the message.
+func (r *Route) accepts(topic string, kind string, data proto.Message) bool {
+	for _, f := range []*Filter{r.filter, r.filters[topic]} {
+		if f == nil {
+			continue
+		}
+		ok, err := f.Match(kind, data)
+		if err != nil {
+			log.Printf("cannot evaluate filter for %s: %v\n", topic, err)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// parseRoutes parses a CSV of routes, where each route has the following format:
+// source-topic:message-kind:dest-topic[|dest-topic...][:flat-dest-topic]
+func parseRoutes(routes string) ([]Route, error) {
+	list := make([]Route, 0)
+	if strings.TrimSpace(routes) == "" {
+		return list, nil
+	}
+	for _, entry := range strings.Split(routes, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		array := strings.Split(entry, ":")
+		if len(array) < 3 || len(array) > 4 {
+			return nil, fmt.Errorf("invalid route %s; expected source-topic:message-kind:dest-topic[|dest-topic...][:flat-dest-topic]", entry)
+		}
+		route := Route{
+			SourceTopic: array[0],
+			MessageKind: array[1],
+			DestTopics:  strings.Split(array[2], "|"),
+		}
+		if len(array) == 4 {
+			route.FlatDestTopic = array[3]
+		}
+		list = append(list, route)
+	}
+	return list, nil
+}
+
+// loadRoutes reads a JSON file with an array of routes.
+func loadRoutes(file string) ([]Route, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read routes file %s: %v", file, err)
+	}
+	list := make([]Route, 0)
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("cannot parse routes file %s: %v", file, err)
+	}
+	return list, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := parseRoutes("OpenNMS-alarms:alarm:alarms-json|alarms-copy:alarms-flat, OpenNMS-nodes:node:nodes-json")
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(routes))
+	assert.Equal(t, "OpenNMS-alarms", routes[0].SourceTopic)
+	assert.Equal(t, alarmKind, routes[0].MessageKind)
+	assert.DeepEqual(t, []string{"alarms-json", "alarms-copy"}, routes[0].DestTopics)
+	assert.Equal(t, "alarms-flat", routes[0].FlatDestTopic)
+	assert.Equal(t, nodeKind, routes[1].MessageKind)
+	assert.Equal(t, "", routes[1].FlatDestTopic)
+
+	_, err = parseRoutes("OpenNMS-alarms:alarm")
+	assert.ErrorContains(t, err, "invalid route")
+}
+
+func TestValidateRoutes(t *testing.T) {
+	cli := &KafkaClient{Routes: "OpenNMS-alarms:alarm:alarms-json, OpenNMS-alarms:alarm:other-json"}
+	assert.ErrorContains(t, cli.validate(), "more than one route")
+
+	cli = &KafkaClient{Routes: "OpenNMS-alarms:unknown:alarms-json"}
+	assert.ErrorContains(t, cli.validate(), "invalid message kind")
+
+	cli = &KafkaClient{Routes: "OpenNMS-Sink-Trap:trap:traps-json", Guarantee: exactlyOnceGuarantee}
+	assert.ErrorContains(t, cli.validate(), "cannot be used with the exactly-once delivery guarantee")
+
+	cli = &KafkaClient{Routes: "OpenNMS-metrics:metric:metrics-json", Rollups: "5m:metrics-5m", Guarantee: exactlyOnceGuarantee}
+	assert.ErrorContains(t, cli.validate(), "cannot be used with the exactly-once delivery guarantee")
+
+	cli = &KafkaClient{SourceTopic: "OpenNMS-events", DestTopic: "events-json", MessageKind: eventKind}
+	assert.NilError(t, cli.validate())
+	assert.Equal(t, 1, len(cli.routes))
+	assert.Equal(t, "events-json", cli.routes["OpenNMS-events"].DestTopics[0])
+}
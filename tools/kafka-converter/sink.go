@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/agalue/kafka-converter/api/sink"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/proto"
+)
+
+// Message kinds for the Minion Sink topics (i.e. OpenNMS.Sink.Trap), where the payload is a SinkMessage with XML content.
+const (
+	trapKind      = "trap"
+	syslogKind    = "syslog"
+	heartbeatKind = "heartbeat"
+)
+
+var sinkKinds = []string{trapKind, syslogKind, heartbeatKind}
+
+// Time to wait for the missing chunks of a SinkMessage before discarding the received ones.
+const sinkChunkTimeout = 5 * time.Minute
+
+// The XML elements that must be rendered as JSON arrays, even when there is only one of them.
+var sinkArrays = map[string]map[string]bool{
+	trapKind:   {"trap-message": true, "result": true},
+	syslogKind: {"messages": true},
+}
+
+func isSinkKind(kind string) bool {
+	return contains(sinkKinds, kind)
+}
+
+type sinkChunks struct {
+	total    int32
+	chunks   map[int32][]byte
+	sources  []*kafka.Message
+	received time.Time
+}
+
+// SinkAssembler reassembles the SinkMessage chunks by message ID.
+// The source messages of the buffered chunks are held until the message is reassembled or discarded.
+type SinkAssembler struct {
+	Timeout time.Duration
+	Hold    func(msg *kafka.Message) // Optional, called when a chunk is buffered
+	Release func(msg *kafka.Message) // Optional, called when a buffered chunk is no longer needed
+	mutex   sync.Mutex
+	pending map[string]*sinkChunks
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewSinkAssembler creates an assembler without pending messages.
+func NewSinkAssembler(timeout time.Duration) *SinkAssembler {
+	return &SinkAssembler{Timeout: timeout, pending: make(map[string]*sinkChunks)}
+}
+
+// Start discards the incomplete messages periodically.
+func (a *SinkAssembler) Start() {
+	a.stop = make(chan struct{})
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				a.Expire(now)
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops discarding messages; the chunks still pending are not released, so they are consumed again after a restart.
+func (a *SinkAssembler) Stop() {
+	if a.stop != nil {
+		close(a.stop)
+		a.wg.Wait()
+	}
+}
+
+// Add processes a given chunk from a given source message received at a given time, and returns the content of the message
+// once all the chunks were received, along with the source messages of the previously buffered chunks (in order of arrival).
+// When the chunks cannot be reassembled, the error is returned along with those source messages.
+// The caller must release the returned source messages once the content is processed.
+func (a *SinkAssembler) Add(msg *sink.SinkMessage, src *kafka.Message, received time.Time) ([]byte, []*kafka.Message, bool, error) {
+	total := msg.GetTotalChunks()
+	if total <= 1 {
+		return msg.Content, nil, true, nil
+	}
+	a.Expire(received)
+	a.mutex.Lock()
+	id := msg.GetMessageId()
+	p, ok := a.pending[id]
+	if !ok {
+		p = &sinkChunks{total: total, chunks: make(map[int32][]byte), received: received}
+		a.pending[id] = p
+	}
+	p.chunks[msg.GetCurrentChunkNumber()] = msg.Content
+	if int32(len(p.chunks)) < p.total {
+		if a.Hold != nil {
+			a.Hold(src)
+		}
+		p.sources = append(p.sources, src)
+		a.mutex.Unlock()
+		return nil, nil, false, nil
+	}
+	delete(a.pending, id)
+	a.mutex.Unlock()
+	var buf bytes.Buffer
+	for i := int32(0); i < p.total; i++ {
+		chunk, ok := p.chunks[i]
+		if !ok {
+			return nil, p.sources, false, fmt.Errorf("invalid chunk numbers for sink message %s; chunk %d of %d is missing", id, i, p.total)
+		}
+		buf.Write(chunk)
+	}
+	return buf.Bytes(), p.sources, true, nil
+}
+
+// Expire discards the pending messages older than the timeout, releasing their chunks.
+func (a *SinkAssembler) Expire(now time.Time) {
+	expired := make([]*sinkChunks, 0)
+	a.mutex.Lock()
+	for id, p := range a.pending {
+		if now.Sub(p.received) > a.Timeout {
+			log.Printf("discarding %d of %d chunks of sink message %s\n", len(p.chunks), p.total, id)
+			delete(a.pending, id)
+			expired = append(expired, p)
+		}
+	}
+	a.mutex.Unlock()
+	for _, p := range expired {
+		a.release(p.sources)
+	}
+}
+
+func (a *SinkAssembler) release(sources []*kafka.Message) {
+	if a.Release == nil {
+		return
+	}
+	for _, src := range sources {
+		a.Release(src)
+	}
+}
+
+// Size returns the number of messages waiting for chunks.
+func (a *SinkAssembler) Size() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return len(a.pending)
+}
+
+// decodeSink converts the XML content of a Sink message of a given kind to JSON.
+func decodeSink(kind string, content []byte) ([]byte, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(content), []byte("<")) {
+		return nil, fmt.Errorf("unsupported %s content; expected XML", kind)
+	}
+	doc, err := xmlToMap(content, sinkArrays[kind])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s content: %v", kind, err)
+	}
+	switch kind {
+	case syslogKind:
+		// The raw syslog messages are encoded as base64
+		for _, m := range asList(doc["messages"]) {
+			if s, ok := decodeText(m["value"]); ok {
+				delete(m, "value")
+				m["message"] = s
+			}
+		}
+	case trapKind:
+		// The values of the varbinds are encoded as base64, with their SNMP type as an attribute
+		for _, trap := range asList(doc["trap-message"]) {
+			results, _ := trap["results"].(map[string]interface{})
+			for _, result := range asList(results["result"]) {
+				if value, ok := result["value"].(map[string]interface{}); ok {
+					if s, ok := decodeText(value["value"]); ok {
+						value["value"] = s
+					}
+				}
+			}
+		}
+	}
+	return json.Marshal(doc)
+}
+
+func asList(value interface{}) []map[string]interface{} {
+	list := make([]map[string]interface{}, 0)
+	items, _ := value.([]interface{})
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			list = append(list, m)
+		}
+	}
+	return list
+}
+
+// decodeText decodes a base64 string, only if the result is printable text.
+func decodeText(value interface{}) (string, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return "", false
+	}
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || !utf8.Valid(data) {
+		return "", false
+	}
+	text := strings.TrimRight(string(data), "\r\n\x00")
+	for _, r := range text {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return "", false
+		}
+	}
+	return text, true
+}
+
+type xmlNode struct {
+	name     string
+	attrs    []xml.Attr
+	children []*xmlNode
+	text     strings.Builder
+}
+
+// xmlToMap converts an XML document to a map with the content of the root element.
+// Attributes and child elements become keys, repeated elements (or the ones in arrays) become lists,
+// and the text of elements with attributes or children is stored as "value".
+func xmlToMap(content []byte, arrays map[string]bool) (map[string]interface{}, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	var root *xmlNode
+	stack := make([]*xmlNode, 0)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: t.Name.Local}
+			for _, attr := range t.Attr {
+				if attr.Name.Space != "xmlns" && attr.Name.Local != "xmlns" && attr.Name.Space != "http://www.w3.org/2001/XMLSchema-instance" {
+					node.attrs = append(node.attrs, attr)
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else if root == nil {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+		}
+	}
+	if root == nil {
+		return nil, fmt.Errorf("empty document")
+	}
+	value := root.value(arrays)
+	if doc, ok := value.(map[string]interface{}); ok {
+		return doc, nil
+	}
+	return map[string]interface{}{"value": value}, nil
+}
+
+func (n *xmlNode) value(arrays map[string]bool) interface{} {
+	text := strings.TrimSpace(n.text.String())
+	if len(n.attrs) == 0 && len(n.children) == 0 {
+		return text
+	}
+	m := make(map[string]interface{})
+	for _, attr := range n.attrs {
+		m[attr.Name.Local] = attr.Value
+	}
+	groups := make(map[string][]interface{})
+	for _, child := range n.children {
+		groups[child.name] = append(groups[child.name], child.value(arrays))
+	}
+	for name, values := range groups {
+		if len(values) == 1 && !arrays[name] {
+			m[name] = values[0]
+		} else {
+			m[name] = values
+		}
+	}
+	if text != "" {
+		m["value"] = text
+	}
+	return m
+}
+
+func (cli *KafkaClient) processSink(msg *kafka.Message, route *Route) {
+	kind := route.MessageKind
+	envelope := &sink.SinkMessage{}
+	if err := proto.Unmarshal(msg.Value, envelope); err != nil {
+		cli.deadLetter(msg, kind, fmt.Errorf("invalid sink message: %v", err))
+		return
+	}
+	content, held, ok, err := cli.sinkAssembler.Add(envelope, msg, time.Now())
+	if err != nil {
+		// Every chunk goes to the dead-letter topic, so the message can be replayed
+		for _, src := range append(held, msg) {
+			cli.deadLetter(src, kind, err)
+		}
+		cli.sinkAssembler.release(held)
+		return
+	}
+	if !ok {
+		return
+	}
+	if len(held) > 0 {
+		// The records are tracked by the first chunk; as offsets are stored in order, the next chunks are not committed before them either
+		defer cli.sinkAssembler.release(held)
+		msg = held[0]
+	}
+	jsonBytes, err := decodeSink(kind, content)
+	if err != nil {
+		cli.deadLetter(msg, kind, err)
+		return
+	}
+	if cli.Debug {
+		log.Printf("%s sink message %s with %d bytes\n", kind, envelope.GetMessageId(), len(content))
+	}
+	cli.forward(msg, route, kind, nil, jsonBytes)
+}
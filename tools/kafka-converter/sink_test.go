@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/agalue/kafka-converter/api/sink"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/proto"
+	"gotest.tools/assert"
+)
+
+func buildChunk(id string, content string, current, total int32) *sink.SinkMessage {
+	return &sink.SinkMessage{
+		MessageId:          proto.String(id),
+		Content:            []byte(content),
+		CurrentChunkNumber: proto.Int32(current),
+		TotalChunks:        proto.Int32(total),
+	}
+}
+
+func TestSinkAssembler(t *testing.T) {
+	a := NewSinkAssembler(time.Minute)
+	held := make(map[*kafka.Message]bool)
+	a.Hold = func(msg *kafka.Message) { held[msg] = true }
+	a.Release = func(msg *kafka.Message) { delete(held, msg) }
+	now := time.Now()
+	src := func() *kafka.Message { return &kafka.Message{} }
+
+	content, sources, ok, err := a.Add(&sink.SinkMessage{MessageId: proto.String("0"), Content: []byte("single")}, src(), now)
+	assert.NilError(t, err)
+	assert.Assert(t, ok)
+	assert.Equal(t, "single", string(content))
+	assert.Equal(t, 0, len(sources))
+
+	// Chunks can arrive out of order, and can be interleaved with other messages
+	first := src()
+	_, _, ok, _ = a.Add(buildChunk("1", "world", 1, 2), first, now)
+	assert.Assert(t, !ok)
+	_, _, ok, _ = a.Add(buildChunk("2", "incomplete", 0, 2), src(), now)
+	assert.Assert(t, !ok)
+	assert.Equal(t, 2, a.Size())
+	assert.Equal(t, 2, len(held))
+	content, sources, ok, _ = a.Add(buildChunk("1", "hello ", 0, 2), src(), now)
+	assert.Assert(t, ok)
+	assert.Equal(t, "hello world", string(content))
+	assert.Equal(t, 1, a.Size())
+
+	// The buffered chunks are held until the caller releases them
+	assert.DeepEqual(t, []*kafka.Message{first}, sources)
+	assert.Assert(t, held[first])
+	a.release(sources)
+	assert.Equal(t, 1, len(held))
+
+	// Incomplete messages are discarded after the timeout, releasing their chunks
+	_, _, ok, _ = a.Add(buildChunk("3", "first", 0, 3), src(), now.Add(2*time.Minute))
+	assert.Assert(t, !ok)
+	assert.Equal(t, 1, a.Size())
+	assert.Equal(t, 1, len(held))
+	a.Expire(now.Add(4 * time.Minute))
+	assert.Equal(t, 0, a.Size())
+	assert.Equal(t, 0, len(held))
+
+	// Invalid chunk numbers are reported along with the buffered chunks
+	first = src()
+	_, _, ok, _ = a.Add(buildChunk("4", "first", 0, 2), first, now)
+	assert.Assert(t, !ok)
+	_, sources, ok, err = a.Add(buildChunk("4", "second", 2, 2), src(), now)
+	assert.ErrorContains(t, err, "invalid chunk numbers for sink message 4")
+	assert.Assert(t, !ok)
+	assert.DeepEqual(t, []*kafka.Message{first}, sources)
+	assert.Equal(t, 0, a.Size())
+}
+
+func TestDecodeSyslog(t *testing.T) {
+	raw := base64.StdEncoding.EncodeToString([]byte("<31>main: 2010-08-19 localhost foo23: load test 23 on tty1\n"))
+	content := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<syslog-message-log source-address="10.0.0.1" source-port="514" system-id="minion01" location="Apex">
+   <messages timestamp="2020-09-13T12:26:40.000Z">` + raw + `</messages>
+</syslog-message-log>`
+	data, err := decodeSink(syslogKind, []byte(content))
+	assert.NilError(t, err)
+	assert.Equal(t, `{"location":"Apex","messages":[{"message":"\u003c31\u003emain: 2010-08-19 localhost foo23: load test 23 on tty1","timestamp":"2020-09-13T12:26:40.000Z"}],"source-address":"10.0.0.1","source-port":"514","system-id":"minion01"}`, string(data))
+}
+
+func TestDecodeTrap(t *testing.T) {
+	value := base64.StdEncoding.EncodeToString([]byte("eth0"))
+	content := `<trap-message-log source-address="10.0.0.1" system-id="minion01" location="Apex">
+   <trap-message>
+      <agent-address>10.0.0.1</agent-address>
+      <community>public</community>
+      <version>v2</version>
+      <timestamp>5000</timestamp>
+      <pdu-length>2</pdu-length>
+      <creation-time>1600000000000</creation-time>
+      <trap-identity enterprise-id=".1.3.6.1.6.3.1.1.5" generic="2" specific="0" trap-oid=".1.3.6.1.6.3.1.1.5.3"/>
+      <results>
+         <result>
+            <base>.1.3.6.1.2.1.2.2.1.2</base>
+            <instance>.1</instance>
+            <value type="4">` + value + `</value>
+         </result>
+      </results>
+   </trap-message>
+</trap-message-log>`
+	data, err := decodeSink(trapKind, []byte(content))
+	assert.NilError(t, err)
+	assert.Equal(t, `{"location":"Apex","source-address":"10.0.0.1","system-id":"minion01","trap-message":[{"agent-address":"10.0.0.1","community":"public","creation-time":"1600000000000","pdu-length":"2","results":{"result":[{"base":".1.3.6.1.2.1.2.2.1.2","instance":".1","value":{"type":"4","value":"eth0"}}]},"timestamp":"5000","trap-identity":{"enterprise-id":".1.3.6.1.6.3.1.1.5","generic":"2","specific":"0","trap-oid":".1.3.6.1.6.3.1.1.5.3"},"version":"v2"}]}`, string(data))
+}
+
+func TestDecodeHeartbeat(t *testing.T) {
+	data, err := decodeSink(heartbeatKind, []byte(`<minion><id>minion01</id><location>Apex</location><timestamp>2020-09-13T12:26:40.000Z</timestamp></minion>`))
+	assert.NilError(t, err)
+	assert.Equal(t, `{"id":"minion01","location":"Apex","timestamp":"2020-09-13T12:26:40.000Z"}`, string(data))
+
+	_, err = decodeSink(heartbeatKind, []byte{0x0a, 0x01})
+	assert.ErrorContains(t, err, "expected XML")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// Valid modes to handle tombstones (records without value) from the source topics.
+const (
+	tombstoneForward = "tombstone" // forward a tombstone with the same key, preserving log compaction
+	tombstoneRecord  = "record"    // emit an explicit delete record
+)
+
+var tombstoneModes = []string{tombstoneForward, tombstoneRecord}
+
+// DeleteRecord represents the removal of the entity associated with a given key (i.e. a deleted alarm or node).
+type DeleteRecord struct {
+	Deleted bool   `json:"deleted"`
+	Key     string `json:"key"`
+}
+
+// processTombstone forwards a tombstone to all the destinations of a given route; filters and transforms are not applied.
+func (cli *KafkaClient) processTombstone(msg *kafka.Message, route *Route) {
+	var value []byte
+	if cli.Tombstones == tombstoneRecord {
+		var err error
+		if value, err = json.Marshal(&DeleteRecord{Deleted: true, Key: string(msg.Key)}); err != nil {
+			log.Printf("cannot build delete record for %s: %v\n", string(msg.Key), err)
+			return
+		}
+	}
+	topics := route.DestTopics
+	if route.FlatDestTopic != "" {
+		topics = append(append([]string{}, topics...), route.FlatDestTopic)
+	}
+	for _, topic := range topics {
+		cli.produce(msg, topic, msg.Key, value)
+	}
+	if cli.lifecycle != nil && route.MessageKind == alarmKind {
+		cli.processLifecycle(msg, nil)
+	}
+	if cli.Debug {
+		log.Printf("tombstone received from %s with key %s\n", msg.TopicPartition, string(msg.Key))
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"gotest.tools/assert"
+)
+
+// readSinkFiles returns the values written to the file sink per topic, one per line.
+func readSinkFiles(t *testing.T, dir string) map[string]string {
+	list, err := ioutil.ReadDir(dir)
+	assert.NilError(t, err)
+	content := make(map[string]string)
+	for _, f := range list {
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		assert.NilError(t, err)
+		topic := f.Name()[:strings.Index(f.Name(), "-")]
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			record := &OutputRecord{}
+			assert.NilError(t, json.Unmarshal([]byte(line), record))
+			content[topic] += string(record.Value) + "\n"
+		}
+	}
+	return content
+}
+
+func TestProcessTombstone(t *testing.T) {
+	source := "OpenNMS_alarms"
+	msg := &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &source}, Key: []byte("uei.opennms.org/nodes/nodeDown::1")}
+	route := &Route{SourceTopic: source, MessageKind: alarmKind, DestTopics: []string{"alarms"}, FlatDestTopic: "flat"}
+	for _, test := range []struct {
+		mode     string
+		expected string
+	}{
+		{tombstoneForward, "null\n"},
+		{tombstoneRecord, `{"deleted":true,"key":"uei.opennms.org/nodes/nodeDown::1"}` + "\n"},
+	} {
+		dir := t.TempDir()
+		sink, err := NewFileSink(dir, 0, 0)
+		assert.NilError(t, err)
+		cli := &KafkaClient{Tombstones: test.mode, LifecycleTopic: "lifecycle", sink: sink, lifecycle: NewAlarmTracker(nil)}
+		cli.lifecycle.Transitions(string(msg.Key), &producer.Alarm{Id: 1, Severity: producer.Severity_MAJOR}, 0)
+		cli.processTombstone(msg, route)
+		sink.Close()
+		content := readSinkFiles(t, dir)
+		assert.Equal(t, test.expected, content["alarms"], test.mode)
+		assert.Equal(t, test.expected, content["flat"], test.mode)
+		assert.Assert(t, strings.Contains(content["lifecycle"], `"type":"deleted"`), test.mode)
+	}
+
+	// Tombstones from other kinds don't affect the tracked alarms
+	dir := t.TempDir()
+	sink, err := NewFileSink(dir, 0, 0)
+	assert.NilError(t, err)
+	cli := &KafkaClient{Tombstones: tombstoneForward, LifecycleTopic: "lifecycle", sink: sink, lifecycle: NewAlarmTracker(nil)}
+	cli.lifecycle.Transitions(string(msg.Key), &producer.Alarm{Id: 1, Severity: producer.Severity_MAJOR}, 0)
+	cli.processTombstone(msg, &Route{SourceTopic: source, MessageKind: nodeKind, DestTopics: []string{"nodes"}})
+	sink.Close()
+	content := readSinkFiles(t, dir)
+	assert.Equal(t, "null\n", content["nodes"])
+	assert.Equal(t, "", content["lifecycle"])
+	assert.Equal(t, 1, cli.lifecycle.Size())
+}
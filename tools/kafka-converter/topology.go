@@ -0,0 +1,410 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+	"github.com/golang/protobuf/proto"
+)
+
+// Valid topology export formats.
+const (
+	graphMLFormat = "graphml"
+	dotFormat     = "dot"
+	jsonFormat    = "json"
+)
+
+var topologyFormats = []string{graphMLFormat, dotFormat, jsonFormat}
+
+// TopologyVertex represents a node or a segment (i.e. a bridge broadcast domain) of the topology.
+type TopologyVertex struct {
+	ID            string `json:"id"`
+	Type          string `json:"type"`
+	Label         string `json:"label"`
+	NodeID        uint64 `json:"node_id,omitempty"`
+	ForeignSource string `json:"foreign_source,omitempty"`
+	ForeignID     string `json:"foreign_id,omitempty"`
+}
+
+// TopologyLink represents an edge between two vertices, discovered through a given protocol.
+type TopologyLink struct {
+	ID         string `json:"id"`
+	Protocol   string `json:"protocol"`
+	Source     string `json:"source"`
+	Target     string `json:"target"`
+	SourcePort string `json:"source_port,omitempty"`
+	TargetPort string `json:"target_port,omitempty"`
+}
+
+// TopologyGraph represents the topology as a nodes/links document.
+type TopologyGraph struct {
+	Nodes []*TopologyVertex `json:"nodes"`
+	Links []*TopologyLink   `json:"links"`
+}
+
+// Topology represents an in-memory view of the topology edges, indexed by the Kafka record key.
+type Topology struct {
+	Topic  string
+	mutex  sync.RWMutex
+	edges  map[string]*producer.TopologyEdge
+	reader *TopicReader
+}
+
+// NewTopology creates an empty topology for a given edges topic.
+func NewTopology(topic string) *Topology {
+	return &Topology{Topic: topic, edges: make(map[string]*producer.TopologyEdge)}
+}
+
+// Start builds the topology from the beginning of the edges topic; when once is true, it stops after reaching the end of the topic.
+func (t *Topology) Start(config *kafka.ConfigMap, once bool) error {
+	t.reader = &TopicReader{Topic: t.Topic, Handler: t.update, Once: once}
+	if err := t.reader.Start(config); err != nil {
+		return err
+	}
+	log.Printf("topology initialized with %d edges\n", t.Size())
+	return nil
+}
+
+// Stop stops reading the edges topic.
+func (t *Topology) Stop() {
+	if t.reader != nil {
+		t.reader.Stop()
+	}
+}
+
+func (t *Topology) update(msg *kafka.Message) {
+	key := string(msg.Key)
+	if msg.Value == nil {
+		t.Update(key, nil)
+		return
+	}
+	edge := &producer.TopologyEdge{}
+	if err := proto.Unmarshal(msg.Value, edge); err != nil {
+		log.Printf("invalid edge message received from %s: %v\n", msg.TopicPartition, err)
+		return
+	}
+	t.Update(key, edge)
+}
+
+// Update adds or replaces the edge associated with a given record key; a nil edge removes it.
+func (t *Topology) Update(key string, edge *producer.TopologyEdge) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if edge == nil {
+		delete(t.edges, key)
+	} else {
+		t.edges[key] = edge
+	}
+}
+
+// Size returns the number of edges.
+func (t *Topology) Size() int {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return len(t.edges)
+}
+
+// Graph builds the graph with the edges of the given protocols (or all of them if empty), sorted by ID.
+func (t *Topology) Graph(protocols []string) *TopologyGraph {
+	vertices := make(map[string]*TopologyVertex)
+	links := make(map[string]*TopologyLink)
+	t.mutex.RLock()
+	for _, edge := range t.edges {
+		ref := edge.GetRef()
+		protocol := ref.GetProtocol().String()
+		if len(protocols) > 0 && !contains(protocols, protocol) {
+			continue
+		}
+		link := &TopologyLink{ID: protocol + ":" + ref.GetId(), Protocol: protocol}
+		switch s := edge.Source.(type) {
+		case *producer.TopologyEdge_SourcePort:
+			link.Source, link.SourcePort = portVertex(vertices, s.SourcePort)
+		case *producer.TopologyEdge_SourceSegment:
+			link.Source = segmentVertex(vertices, s.SourceSegment)
+		case *producer.TopologyEdge_SourceNode:
+			link.Source = nodeVertex(vertices, s.SourceNode)
+		}
+		switch s := edge.Target.(type) {
+		case *producer.TopologyEdge_TargetPort:
+			link.Target, link.TargetPort = portVertex(vertices, s.TargetPort)
+		case *producer.TopologyEdge_TargetSegment:
+			link.Target = segmentVertex(vertices, s.TargetSegment)
+		case *producer.TopologyEdge_TargetNode:
+			link.Target = nodeVertex(vertices, s.TargetNode)
+		}
+		if link.Source == "" || link.Target == "" {
+			continue
+		}
+		links[link.ID] = link
+	}
+	t.mutex.RUnlock()
+	g := &TopologyGraph{
+		Nodes: make([]*TopologyVertex, 0, len(vertices)),
+		Links: make([]*TopologyLink, 0, len(links)),
+	}
+	for _, v := range vertices {
+		g.Nodes = append(g.Nodes, v)
+	}
+	for _, l := range links {
+		g.Links = append(g.Links, l)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.Slice(g.Links, func(i, j int) bool { return g.Links[i].ID < g.Links[j].ID })
+	return g
+}
+
+// criteriaVertex adds the vertex of a node given its identity, and returns its ID; the label is updated if known.
+func criteriaVertex(vertices map[string]*TopologyVertex, id uint64, foreignSource, foreignID, label string) string {
+	var key string
+	if id > 0 {
+		key = nodeIDKey(id)
+	} else if foreignSource != "" && foreignID != "" {
+		key = nodeFSKey(foreignSource, foreignID)
+	} else {
+		return ""
+	}
+	v, ok := vertices[key]
+	if !ok {
+		v = &TopologyVertex{ID: key, Type: "node", NodeID: id, ForeignSource: foreignSource, ForeignID: foreignID}
+		vertices[key] = v
+	}
+	if label != "" {
+		v.Label = label
+	}
+	if v.Label == "" {
+		v.Label = key
+	}
+	return key
+}
+
+func nodeVertex(vertices map[string]*TopologyVertex, node *producer.Node) string {
+	return criteriaVertex(vertices, node.GetId(), node.GetForeignSource(), node.GetForeignId(), node.GetLabel())
+}
+
+// portVertex adds the vertex of the node that owns a given port, and returns its ID and the port name.
+func portVertex(vertices map[string]*TopologyVertex, port *producer.TopologyPort) (string, string) {
+	c := port.GetNodeCriteria()
+	name := port.GetIfName()
+	if name == "" && port.GetIfIndex() > 0 {
+		name = strconv.FormatUint(port.GetIfIndex(), 10)
+	}
+	if name == "" {
+		name = port.GetAddress()
+	}
+	return criteriaVertex(vertices, c.GetId(), c.GetForeignSource(), c.GetForeignId(), ""), name
+}
+
+func segmentVertex(vertices map[string]*TopologyVertex, segment *producer.TopologySegment) string {
+	ref := segment.GetRef()
+	if ref.GetId() == "" {
+		return ""
+	}
+	key := "segment:" + ref.GetId()
+	if _, ok := vertices[key]; !ok {
+		vertices[key] = &TopologyVertex{ID: key, Type: "segment", Label: ref.GetProtocol().String() + " " + ref.GetId()}
+	}
+	return key
+}
+
+// Export writes the graph using a given format.
+func (g *TopologyGraph) Export(w io.Writer, format string) error {
+	switch format {
+	case graphMLFormat:
+		return g.writeGraphML(w)
+	case dotFormat:
+		return g.writeDOT(w)
+	case jsonFormat:
+		return json.NewEncoder(w).Encode(g)
+	}
+	return fmt.Errorf("invalid topology format %s. Valid options: %s", format, strings.Join(topologyFormats, ", "))
+}
+
+func (g *TopologyGraph) writeDOT(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("graph topology {\n")
+	for _, v := range g.Nodes {
+		shape := "box"
+		if v.Type == "segment" {
+			shape = "ellipse"
+		}
+		fmt.Fprintf(&sb, "  %q [label=%q, shape=%s];\n", v.ID, v.Label, shape)
+	}
+	for _, l := range g.Links {
+		label := l.Protocol
+		for i, port := range []string{l.SourcePort, l.TargetPort} {
+			if port != "" && i > 0 && l.SourcePort != "" {
+				label += " - " + port
+			} else if port != "" {
+				label += " " + port
+			}
+		}
+		fmt.Fprintf(&sb, "  %q -- %q [label=%q, protocol=%q];\n", l.Source, l.Target, label, l.Protocol)
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+func (g *TopologyGraph) writeGraphML(w io.Writer) error {
+	doc := graphMLDocument{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "type", For: "node", Name: "type", Type: "string"},
+			{ID: "protocol", For: "edge", Name: "protocol", Type: "string"},
+			{ID: "sourcePort", For: "edge", Name: "sourcePort", Type: "string"},
+			{ID: "targetPort", For: "edge", Name: "targetPort", Type: "string"},
+		},
+		Graph: graphMLGraph{ID: "topology", EdgeDefault: "undirected"},
+	}
+	for _, v := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID:   v.ID,
+			Data: []graphMLData{{"label", v.Label}, {"type", v.Type}},
+		})
+	}
+	for _, l := range g.Links {
+		data := []graphMLData{{"protocol", l.Protocol}}
+		if l.SourcePort != "" {
+			data = append(data, graphMLData{"sourcePort", l.SourcePort})
+		}
+		if l.TargetPort != "" {
+			data = append(data, graphMLData{"targetPort", l.TargetPort})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{ID: l.ID, Source: l.Source, Target: l.Target, Data: data})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(&doc)
+}
+
+// parseProtocols parses a CSV of topology protocols.
+func parseProtocols(value string) ([]string, error) {
+	protocols := make([]string, 0)
+	for _, p := range strings.Split(value, ",") {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if _, ok := producer.TopologyRef_Protocol_value[p]; !ok {
+			return nil, fmt.Errorf("invalid protocol %s", p)
+		}
+		protocols = append(protocols, p)
+	}
+	return protocols, nil
+}
+
+// ServeHTTP implements the http.Handler interface; the format and protocols are given by the format and protocol query parameters.
+func (t *Topology) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	format := params.Get("format")
+	if format == "" {
+		format = jsonFormat
+	}
+	protocols, err := parseProtocols(params.Get("protocol"))
+	if err == nil && !contains(topologyFormats, format) {
+		err = fmt.Errorf("invalid format %s", format)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch format {
+	case graphMLFormat:
+		w.Header().Set("Content-Type", "application/graphml+xml")
+	case dotFormat:
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+	default:
+		w.Header().Set("Content-Type", "application/json")
+	}
+	if err := t.Graph(protocols).Export(w, format); err != nil {
+		log.Printf("cannot write topology: %v\n", err)
+	}
+}
+
+// topologyCommand exports the topology from an edges topic once.
+func topologyCommand(args []string) error {
+	cli := &KafkaClient{}
+	fs := flag.NewFlagSet("topology", flag.ExitOnError)
+	fs.StringVar(&cli.Bootstrap, "bootstrap", "localhost:9092", "kafka bootstrap server")
+	fs.StringVar(&cli.ConsumerSettings, "consumer-params", "", "optional kafka consumer parameters as a CSV of Key-Value pairs")
+	topic := fs.String("topic", "OpenNMS-topology-edges", "kafka topic with OpenNMS Producer GPB edges")
+	format := fs.String("format", jsonFormat, "export format; valid options: "+strings.Join(topologyFormats, ", "))
+	protocol := fs.String("protocol", "", "optional CSV of protocols to export; valid options: LLDP, CDP, OSPF, ISIS, BRIDGE, USERDEFINED")
+	output := fs.String("output", "", "optional output file (defaults to the standard output)")
+	fs.Parse(args)
+	if !contains(topologyFormats, *format) {
+		return fmt.Errorf("invalid topology format %s. Valid options: %s", *format, strings.Join(topologyFormats, ", "))
+	}
+	protocols, err := parseProtocols(*protocol)
+	if err != nil {
+		return err
+	}
+	topology := NewTopology(*topic)
+	config := cli.getKafkaConfig(cli.ConsumerSettings)
+	config.SetKey("group.id", "kafka-converter-topology")
+	if err := topology.Start(config, true); err != nil {
+		return err
+	}
+	w := os.Stdout
+	if *output != "" {
+		if w, err = os.Create(*output); err != nil {
+			return fmt.Errorf("cannot create %s: %v", *output, err)
+		}
+		defer w.Close()
+	}
+	return topology.Graph(protocols).Export(w, *format)
+}
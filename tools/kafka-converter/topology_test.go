@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"gotest.tools/assert"
+)
+
+func buildTopology() *Topology {
+	topology := NewTopology("OpenNMS-topology-edges")
+	topology.Update("lldp-1", &producer.TopologyEdge{
+		Ref:    &producer.TopologyRef{Id: "1", Protocol: producer.TopologyRef_LLDP},
+		Source: &producer.TopologyEdge_SourcePort{SourcePort: &producer.TopologyPort{IfName: "eth0", NodeCriteria: &producer.NodeCriteria{Id: 1}}},
+		Target: &producer.TopologyEdge_TargetNode{TargetNode: &producer.Node{Id: 2, Label: "switch01"}},
+	})
+	topology.Update("bridge-2", &producer.TopologyEdge{
+		Ref:    &producer.TopologyRef{Id: "2", Protocol: producer.TopologyRef_BRIDGE},
+		Source: &producer.TopologyEdge_SourcePort{SourcePort: &producer.TopologyPort{IfIndex: 3, NodeCriteria: &producer.NodeCriteria{ForeignSource: "Production", ForeignId: "srv01"}}},
+		Target: &producer.TopologyEdge_TargetSegment{TargetSegment: &producer.TopologySegment{Ref: &producer.TopologyRef{Id: "10", Protocol: producer.TopologyRef_BRIDGE}}},
+	})
+	return topology
+}
+
+func TestTopologyGraph(t *testing.T) {
+	topology := buildTopology()
+	g := topology.Graph(nil)
+	assert.Equal(t, 4, len(g.Nodes))
+	assert.Equal(t, 2, len(g.Links))
+	assert.Equal(t, "BRIDGE:2", g.Links[0].ID)
+	assert.Equal(t, "fs:Production:srv01", g.Links[0].Source)
+	assert.Equal(t, "3", g.Links[0].SourcePort)
+	assert.Equal(t, "segment:10", g.Links[0].Target)
+	assert.Equal(t, "id:1", g.Links[1].Source)
+	assert.Equal(t, "eth0", g.Links[1].SourcePort)
+	assert.Equal(t, "id:2", g.Links[1].Target)
+	assert.Equal(t, "switch01", g.Nodes[2].Label)
+
+	g = topology.Graph([]string{"LLDP"})
+	assert.Equal(t, 2, len(g.Nodes))
+	assert.Equal(t, 1, len(g.Links))
+
+	topology.Update("lldp-1", nil)
+	assert.Equal(t, 1, topology.Size())
+}
+
+func TestTopologyExport(t *testing.T) {
+	g := buildTopology().Graph([]string{"LLDP"})
+	var buf bytes.Buffer
+	assert.NilError(t, g.Export(&buf, dotFormat))
+	assert.Equal(t, `graph topology {
+  "id:1" [label="id:1", shape=box];
+  "id:2" [label="switch01", shape=box];
+  "id:1" -- "id:2" [label="LLDP eth0", protocol="LLDP"];
+}
+`, buf.String())
+
+	buf.Reset()
+	assert.NilError(t, g.Export(&buf, graphMLFormat))
+	assert.Assert(t, strings.Contains(buf.String(), `<edge id="LLDP:1" source="id:1" target="id:2">`))
+
+	buf.Reset()
+	assert.NilError(t, g.Export(&buf, jsonFormat))
+	assert.Equal(t, `{"nodes":[{"id":"id:1","type":"node","label":"id:1","node_id":1},{"id":"id:2","type":"node","label":"switch01","node_id":2}],"links":[{"id":"LLDP:1","protocol":"LLDP","source":"id:1","target":"id:2","source_port":"eth0"}]}`+"\n", buf.String())
+
+	assert.ErrorContains(t, g.Export(&buf, "svg"), "invalid topology format")
+	_, err := parseProtocols("lldp, foo")
+	assert.ErrorContains(t, err, "invalid protocol")
+}
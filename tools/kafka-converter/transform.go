@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Transform represents a Go template that reshapes the JSON representation of a message.
+//
+// The template is executed against the converted message decoded as a generic JSON object, so the field
+// names depend on the JSON format, and it must produce a valid JSON document. For instance:
+//
+//	{"id": {{.id}}, "uei": {{json .uei}}, "severity": {{json (enum "Severity" .severity)}}}
+//	{{json (omit . "lastEvent" "relatedAlarm")}}
+//
+// Besides the standard functions, the following are available: json, dict, pick, omit, rename, enum, time, lower and upper.
+type Transform struct {
+	Name     string
+	template *template.Template
+}
+
+// NewTransform parses a given template; when the text starts with @, the template is read from the referenced file.
+func NewTransform(name, text string) (*Transform, error) {
+	if strings.HasPrefix(text, "@") {
+		data, err := ioutil.ReadFile(text[1:])
+		if err != nil {
+			return nil, fmt.Errorf("cannot read transform file %s: %v", text[1:], err)
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New(name).Funcs(transformFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transform for %s: %v", name, err)
+	}
+	return &Transform{Name: name, template: tmpl}, nil
+}
+
+// Apply returns the result of the template for a given JSON message.
+func (t *Transform) Apply(jsonBytes []byte) ([]byte, error) {
+	var content interface{}
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.UseNumber()
+	if err := decoder.Decode(&content); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := t.template.Execute(&out, content); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, out.Bytes()); err != nil {
+		return nil, fmt.Errorf("transform for %s produced invalid JSON: %v", t.Name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+var transformFuncs = template.FuncMap{
+	"json":   toJSON,
+	"dict":   dict,
+	"pick":   pick,
+	"omit":   omit,
+	"rename": rename,
+	"enum":   enumName,
+	"time":   formatTime,
+	"lower":  strings.ToLower,
+	"upper":  strings.ToUpper,
+}
+
+// toJSON returns the JSON representation of a value, to be embedded on the template output.
+func toJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// dict builds an object from a list of key-value pairs.
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict requires an even number of arguments")
+	}
+	result := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings")
+		}
+		result[key] = pairs[i+1]
+	}
+	return result, nil
+}
+
+// pick returns a copy of an object with only the given keys.
+func pick(value interface{}, keys ...string) map[string]interface{} {
+	result := make(map[string]interface{})
+	if obj, ok := value.(map[string]interface{}); ok {
+		for _, key := range keys {
+			if v, ok := obj[key]; ok {
+				result[key] = v
+			}
+		}
+	}
+	return result
+}
+
+// omit returns a copy of an object without the given keys.
+func omit(value interface{}, keys ...string) map[string]interface{} {
+	result := make(map[string]interface{})
+	if obj, ok := value.(map[string]interface{}); ok {
+		for key, v := range obj {
+			if !contains(keys, key) {
+				result[key] = v
+			}
+		}
+	}
+	return result
+}
+
+// rename returns a copy of an object with the keys renamed based on a list of old-new pairs.
+func rename(value interface{}, pairs ...string) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("rename requires pairs of keys")
+	}
+	result := omit(value)
+	for i := 0; i < len(pairs); i += 2 {
+		if v, ok := result[pairs[i]]; ok {
+			delete(result, pairs[i])
+			result[pairs[i+1]] = v
+		}
+	}
+	return result, nil
+}
+
+// enumName returns the name of a value of a given enum (i.e. Severity or Alarm.Type); names are returned as they are.
+func enumName(enum string, value interface{}) (string, error) {
+	et, err := protoregistry.GlobalTypes.FindEnumByName(protoreflect.FullName(enum))
+	if err != nil {
+		return "", fmt.Errorf("unknown enum %s", enum)
+	}
+	var number int64
+	switch v := value.(type) {
+	case json.Number:
+		if number, err = v.Int64(); err != nil {
+			return "", err
+		}
+	case string:
+		return v, nil
+	case nil:
+	default:
+		return "", fmt.Errorf("invalid value %v for enum %s", value, enum)
+	}
+	if ev := et.Descriptor().Values().ByNumber(protoreflect.EnumNumber(number)); ev != nil {
+		return string(ev.Name()), nil
+	}
+	return strconv.FormatInt(number, 10), nil
+}
+
+// formatTime converts milliseconds since epoch to an RFC3339 string; strings are returned as they are.
+func formatTime(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case json.Number:
+		ms, err := v.Int64()
+		if err != nil {
+			return "", err
+		}
+		return time.Unix(0, ms*int64(time.Millisecond)).UTC().Format(rfc3339Millis), nil
+	case nil:
+		return "", nil
+	case string:
+		if ts, ok := toRFC3339(v); ok {
+			return ts, nil
+		}
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid timestamp %v", value)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/agalue/kafka-converter/api/producer"
+	"gotest.tools/assert"
+)
+
+func TestTransform(t *testing.T) {
+	encoder := &JSONEncoder{Format: camelJSONFormat}
+	alarm := &producer.Alarm{
+		Id:            1,
+		Uei:           "uei.opennms.org/nodes/nodeDown",
+		Severity:      producer.Severity_MAJOR,
+		LastEventTime: 1600000000000,
+		NodeCriteria:  &producer.NodeCriteria{Id: 10},
+	}
+	jsonBytes, err := encoder.Marshal(alarm)
+	assert.NilError(t, err)
+
+	tr, err := NewTransform("alarms-json", `{"id": {{.id}}, "uei": {{json .uei}}, "severity": {{json (lower (enum "Severity" .severity))}}, "time": {{json (time .lastEventTime)}}, "node": {{.nodeCriteria.id}}}`)
+	assert.NilError(t, err)
+	result, err := tr.Apply(jsonBytes)
+	assert.NilError(t, err)
+	assert.Equal(t, `{"id":1,"uei":"uei.opennms.org/nodes/nodeDown","severity":"major","time":"2020-09-13T12:26:40.000Z","node":10}`, string(result))
+
+	tr, err = NewTransform("alarms-json", `{{json (rename (pick . "id" "uei" "severity") "uei" "eventUei")}}`)
+	assert.NilError(t, err)
+	result, err = tr.Apply(jsonBytes)
+	assert.NilError(t, err)
+	assert.Equal(t, `{"eventUei":"uei.opennms.org/nodes/nodeDown","id":"1","severity":5}`, string(result))
+
+	encoder = &JSONEncoder{Format: legacyJSONFormat}
+	jsonBytes, err = encoder.Marshal(alarm)
+	assert.NilError(t, err)
+	tr, err = NewTransform("alarms-json", `{{json (dict "severity" (enum "Severity" .severity) "other" (omit . "id" "uei" "severity" "last_event_time" "node_criteria"))}}`)
+	assert.NilError(t, err)
+	result, err = tr.Apply(jsonBytes)
+	assert.NilError(t, err)
+	assert.Equal(t, `{"other":{},"severity":"MAJOR"}`, string(result))
+
+	tr, err = NewTransform("alarms-json", `{"id": {{.id}}`)
+	assert.NilError(t, err)
+	_, err = tr.Apply(jsonBytes)
+	assert.ErrorContains(t, err, "invalid JSON")
+
+	_, err = NewTransform("alarms-json", `{{.id`)
+	assert.ErrorContains(t, err, "invalid transform")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/kafka"
+)
+
+// TumblingWindow keeps the state per key of fixed-size, non-overlapping windows.
+// Windows are closed based on event time, once the highest timestamp seen minus the allowed lateness passes their end.
+// Open windows are never emitted, so their source messages are held until they are closed, and consumed again after a restart.
+type TumblingWindow struct {
+	Window    time.Duration
+	Lateness  time.Duration
+	Hold      func(msg *kafka.Message) // Optional, called when a message is added to a window
+	watermark int64
+	windows   map[int64]*window
+	dropped   int
+}
+
+// window is the state of a given window, and the source messages added to it.
+type window struct {
+	start   int64
+	end     int64
+	states  map[string]interface{}
+	sources []*kafka.Message
+}
+
+// NewTumblingWindow creates a new tumbling window of a given size.
+func NewTumblingWindow(size time.Duration, lateness time.Duration) *TumblingWindow {
+	return &TumblingWindow{
+		Window:   size,
+		Lateness: lateness,
+		windows:  make(map[int64]*window),
+	}
+}
+
+// open returns the window for a given timestamp (in milliseconds) and adds the source message to it; returns nil if the window is already closed.
+func (w *TumblingWindow) open(msg *kafka.Message, timestamp int64) *window {
+	size := w.Window.Milliseconds()
+	start := timestamp - timestamp%size
+	if start+size <= w.watermark {
+		w.dropped++
+		return nil
+	}
+	win, ok := w.windows[start]
+	if !ok {
+		win = &window{start: start, end: start + size, states: make(map[string]interface{})}
+		w.windows[start] = win
+	}
+	// Keep only the delivery of the message, as the window might be open for a long time
+	src := &kafka.Message{TopicPartition: msg.TopicPartition, Opaque: msg.Opaque}
+	if w.Hold != nil {
+		w.Hold(src)
+	}
+	win.sources = append(win.sources, src)
+	return win
+}
+
+// state returns the state of a given key, created by init when missing.
+func (win *window) state(key string, init func() interface{}) interface{} {
+	s, ok := win.states[key]
+	if !ok {
+		s = init()
+		win.states[key] = s
+	}
+	return s
+}
+
+// advance moves the watermark based on a given timestamp (in milliseconds), and returns the states of the windows closed as a result,
+// sorted by end time and key, together with their source messages, which must be released once the states are processed.
+func (w *TumblingWindow) advance(timestamp int64) ([]interface{}, []*kafka.Message) {
+	if wm := timestamp - w.Lateness.Milliseconds(); wm > w.watermark {
+		w.watermark = wm
+	}
+	closed := make([]*window, 0)
+	for start, win := range w.windows {
+		if win.end <= w.watermark {
+			closed = append(closed, win)
+			delete(w.windows, start)
+		}
+	}
+	sort.Slice(closed, func(i, j int) bool {
+		return closed[i].end < closed[j].end
+	})
+	states := make([]interface{}, 0)
+	sources := make([]*kafka.Message, 0)
+	for _, win := range closed {
+		keys := make([]string, 0, len(win.states))
+		for key := range win.states {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			states = append(states, win.states[key])
+		}
+		sources = append(sources, win.sources...)
+	}
+	return states, sources
+}